@@ -0,0 +1,29 @@
+package compressor
+
+import (
+	"context"
+	"io"
+)
+
+type Compressor interface {
+	Compress(src string, dst string) error
+}
+
+// ContextCompressor is a Compressor that stops when ctx is cancelled.
+type ContextCompressor interface {
+	Compressor
+	CompressContext(ctx context.Context, src string, dst string) error
+}
+
+// WriterCompressor writes an archive to w rather than a file.
+type WriterCompressor interface {
+	CompressTo(src string, w io.Writer) error
+	CompressToContext(ctx context.Context, src string, w io.Writer) error
+}
+
+// ArchiveCompressor is every way of writing an archive, which the
+// compressors in this package implement.
+type ArchiveCompressor interface {
+	ContextCompressor
+	WriterCompressor
+}
@@ -0,0 +1,84 @@
+package compressor
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// compressToFile writes the archive to a temporary file beside dest and
+// renames it into place once it is complete, so that dest is never left
+// truncated.
+func compressToFile(ctx context.Context, compressor WriterCompressor, src string, dest string, opts []Option) error {
+	o := newOptions(opts)
+
+	fw, err := createTemp(dest)
+	if err != nil {
+		return err
+	}
+
+	err = writeTemp(ctx, compressor, src, fw, o.fsync)
+	if err != nil {
+		os.Remove(fw.Name())
+		return err
+	}
+
+	err = os.Rename(fw.Name(), dest)
+	if err != nil {
+		os.Remove(fw.Name())
+		return err
+	}
+
+	if o.fsync {
+		return syncDir(filepath.Dir(dest))
+	}
+
+	return nil
+}
+
+func writeTemp(ctx context.Context, compressor WriterCompressor, src string, fw *os.File, fsync bool) error {
+	err := compressor.CompressToContext(ctx, src, fw)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		err = ctxErr
+	}
+
+	if err == nil && fsync {
+		err = fw.Sync()
+	}
+
+	return errors.Join(err, fw.Close())
+}
+
+// createTemp creates a file to write dest through in the same directory,
+// with the permissions os.Create would give dest: those of dest if it
+// exists, and 0666 less the umask otherwise.
+func createTemp(dest string) (*os.File, error) {
+	dir, base := filepath.Split(dest)
+
+	for range 10 {
+		name := filepath.Join(dir, "."+base+"-"+strconv.FormatUint(rand.Uint64(), 36)+".tmp")
+		fw, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if info, err := os.Stat(dest); err == nil {
+			err = fw.Chmod(info.Mode().Perm())
+			if err != nil {
+				fw.Close()
+				os.Remove(name)
+				return nil, err
+			}
+		}
+
+		return fw, nil
+	}
+
+	return nil, &os.PathError{Op: "createtemp", Path: dest, Err: os.ErrExist}
+}
@@ -0,0 +1,94 @@
+package compressor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+func NewDetectable(opts ...Option) ArchiveCompressor {
+	return &detectableCompressor{opts: opts}
+}
+
+type detectableCompressor struct {
+	opts []Option
+}
+
+func (compressor *detectableCompressor) Compress(src string, dest string) error {
+	return compressor.CompressContext(context.Background(), src, dest)
+}
+
+func (compressor *detectableCompressor) CompressContext(ctx context.Context, src string, dest string) error {
+	detected, err := lookupFormat(dest, compressor.opts)
+	if err != nil {
+		return err
+	}
+
+	return compressWith(ctx, detected, src, dest)
+}
+
+func (compressor *detectableCompressor) CompressTo(src string, w io.Writer) error {
+	return compressor.CompressToContext(context.Background(), src, w)
+}
+
+// CompressToContext can only detect the format of writers that know their
+// name, such as *os.File.
+func (compressor *detectableCompressor) CompressToContext(ctx context.Context, src string, w io.Writer) error {
+	named, ok := w.(interface{ Name() string })
+	if !ok {
+		return errors.New("cannot detect the archive type of an unnamed writer")
+	}
+
+	detected, err := lookupFormat(named.Name(), compressor.opts)
+	if err != nil {
+		return err
+	}
+
+	if writerCompressor, ok := detected.(WriterCompressor); ok {
+		return writerCompressor.CompressToContext(ctx, src, w)
+	}
+
+	return compressThroughFile(ctx, detected, src, filepath.Base(named.Name()), w)
+}
+
+// compressWith compresses with a registered compressor, through
+// CompressContext if it implements it.
+func compressWith(ctx context.Context, compressor Compressor, src string, dest string) error {
+	if contextCompressor, ok := compressor.(ContextCompressor); ok {
+		return contextCompressor.CompressContext(ctx, src, dest)
+	}
+
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	return compressor.Compress(src, dest)
+}
+
+// compressThroughFile writes the archive as name in a temporary directory,
+// for a registered compressor that can only write files, and copies it to w.
+func compressThroughFile(ctx context.Context, compressor Compressor, src string, name string, w io.Writer) error {
+	dir, err := os.MkdirTemp("", "archiver-stream")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	dest := filepath.Join(dir, name)
+	err = compressWith(ctx, compressor, src, dest)
+	if err != nil {
+		return err
+	}
+
+	archive, err := os.Open(dest)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	_, err = io.Copy(w, archive)
+	return err
+}
@@ -0,0 +1,175 @@
+package compressor_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "code.cloudfoundry.org/archiver/compressor"
+	"code.cloudfoundry.org/archiver/compressor/fake_compressor"
+	"code.cloudfoundry.org/archiver/extractor"
+)
+
+var _ = Describe("Detectable Compressor", func() {
+	var compressor ArchiveCompressor
+	var destDir string
+	var victimDir string
+
+	BeforeEach(func() {
+		var err error
+
+		compressor = NewDetectable()
+
+		destDir, err = os.MkdirTemp("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		victimDir, err = os.MkdirTemp("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		err = os.WriteFile(filepath.Join(victimDir, "some_file"), []byte("stuff"), 0644)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(destDir)
+		os.RemoveAll(victimDir)
+	})
+
+	DescribeTable("choosing the format from the dest extension",
+		func(name string, magic []byte) {
+			destFile := filepath.Join(destDir, name)
+
+			err := compressor.Compress(victimDir+"/", destFile)
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := os.ReadFile(destFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(HavePrefix(string(magic)))
+
+			finalReadingDir, err := os.MkdirTemp(destDir, "final")
+			Expect(err).NotTo(HaveOccurred())
+
+			err = extractor.NewDetectable().Extract(destFile, finalReadingDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(finalReadingDir, "some_file")).To(BeAnExistingFile())
+		},
+		Entry(".tgz", "archive.tgz", []byte{0x1f, 0x8b}),
+		Entry(".tar.gz", "archive.tar.gz", []byte{0x1f, 0x8b}),
+		Entry(".zip", "archive.zip", []byte("PK\x03\x04")),
+		Entry("upper case", "ARCHIVE.ZIP", []byte("PK\x03\x04")),
+		Entry(".tar", "archive.tar", []byte("./")),
+	)
+
+	It("detects the format of a named writer", func() {
+		destFile, err := os.Create(filepath.Join(destDir, "archive.zip"))
+		Expect(err).NotTo(HaveOccurred())
+		defer destFile.Close()
+
+		err = compressor.CompressTo(victimDir+"/", destFile)
+		Expect(err).NotTo(HaveOccurred())
+
+		contents, err := os.ReadFile(destFile.Name())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(contents).To(HavePrefix("PK\x03\x04"))
+	})
+
+	It("returns an error for an unnamed writer", func() {
+		err := compressor.CompressTo(victimDir, new(bytes.Buffer))
+		Expect(err).To(HaveOccurred())
+	})
+
+	Context("when the extension is unknown", func() {
+		It("returns an unsupported format error listing the known extensions", func() {
+			destFile := filepath.Join(destDir, "archive.rar")
+
+			err := compressor.Compress(victimDir, destFile)
+			Expect(err).To(MatchError(ErrUnsupportedFormat))
+
+			var formatErr *UnsupportedFormatError
+			Expect(errors.As(err, &formatErr)).To(BeTrue())
+			Expect(formatErr.Dest).To(Equal(destFile))
+			Expect(formatErr.Extensions).To(ContainElements(".tar", ".tar.gz", ".tgz", ".zip"))
+
+			Expect(destFile).NotTo(BeAnExistingFile())
+		})
+	})
+
+	Context("when a format is registered", func() {
+		var registered *fake_compressor.FakeCompressor
+
+		BeforeEach(func() {
+			registered = &fake_compressor.FakeCompressor{}
+			Register("jar", func(...Option) Compressor {
+				return registered
+			})
+		})
+
+		AfterEach(func() {
+			Unregister("jar")
+		})
+
+		It("compresses with the registered compressor", func() {
+			destFile := filepath.Join(destDir, "app.jar")
+
+			err := compressor.Compress(victimDir, destFile)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(registered.Src).To(Equal(victimDir))
+			Expect(registered.Dest).To(Equal(destFile))
+		})
+
+		It("calls the factory without holding the registry", func() {
+			Register("jar", func(...Option) Compressor {
+				Register("war", func(...Option) Compressor {
+					return registered
+				})
+				return registered
+			})
+			DeferCleanup(Unregister, "war")
+
+			done := make(chan error)
+			go func() {
+				done <- compressor.Compress(victimDir, filepath.Join(destDir, "app.jar"))
+			}()
+
+			Eventually(done).Should(Receive(Succeed()))
+		})
+	})
+
+	Context("when a registered compressor can only write files", func() {
+		BeforeEach(func() {
+			Register("jar", func(...Option) Compressor {
+				return fileOnlyCompressor{}
+			})
+		})
+
+		AfterEach(func() {
+			Unregister("jar")
+		})
+
+		It("compresses to a writer through a temporary file", func() {
+			destFile, err := os.Create(filepath.Join(destDir, "app.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			defer destFile.Close()
+
+			err = compressor.CompressTo(victimDir, destFile)
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := os.ReadFile(destFile.Name())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal("archive of " + victimDir))
+		})
+	})
+})
+
+// fileOnlyCompressor implements nothing beyond Compressor.
+type fileOnlyCompressor struct{}
+
+func (fileOnlyCompressor) Compress(src string, dest string) error {
+	return os.WriteFile(dest, []byte("archive of "+src), 0644)
+}
@@ -1,13 +1,23 @@
 package fake_compressor
 
+import (
+	"context"
+	"io"
+)
+
 type FakeCompressor struct {
-	Src  string
-	Dest string
+	Src    string
+	Dest   string
+	Writer io.Writer
 
 	CompressError error
 }
 
 func (compressor *FakeCompressor) Compress(src, dest string) error {
+	return compressor.CompressContext(context.Background(), src, dest)
+}
+
+func (compressor *FakeCompressor) CompressContext(ctx context.Context, src, dest string) error {
 	if compressor.CompressError != nil {
 		return compressor.CompressError
 	}
@@ -16,3 +26,17 @@ func (compressor *FakeCompressor) Compress(src, dest string) error {
 	compressor.Dest = dest
 	return nil
 }
+
+func (compressor *FakeCompressor) CompressTo(src string, w io.Writer) error {
+	return compressor.CompressToContext(context.Background(), src, w)
+}
+
+func (compressor *FakeCompressor) CompressToContext(ctx context.Context, src string, w io.Writer) error {
+	if compressor.CompressError != nil {
+		return compressor.CompressError
+	}
+
+	compressor.Src = src
+	compressor.Writer = w
+	return nil
+}
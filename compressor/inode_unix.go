@@ -0,0 +1,23 @@
+//go:build unix
+
+package compressor
+
+import (
+	"os"
+	"syscall"
+)
+
+type inode struct {
+	dev uint64
+	ino uint64
+}
+
+// hardLinkID identifies a file that has further hard links to it.
+func hardLinkID(fi os.FileInfo) (inode, bool) {
+	stat, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok || stat.Nlink < 2 {
+		return inode{}, false
+	}
+
+	return inode{dev: uint64(stat.Dev), ino: uint64(stat.Ino)}, true
+}
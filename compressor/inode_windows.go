@@ -0,0 +1,11 @@
+//go:build windows
+
+package compressor
+
+import "os"
+
+type inode struct{}
+
+func hardLinkID(_ os.FileInfo) (inode, bool) {
+	return inode{}, false
+}
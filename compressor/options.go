@@ -0,0 +1,210 @@
+package compressor
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/archiver/idmap"
+)
+
+type Option func(*options)
+
+type options struct {
+	reproducible bool
+	excludes     []string
+	includes     []string
+	ignoreFile   string
+	xattrAllow   []string
+	xattrDeny    []string
+
+	uidMap     idmap.Mapping
+	gidMap     idmap.Mapping
+	forceOwner bool
+	uid, gid   int
+
+	fsync bool
+
+	epoch time.Time
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// prepare resolves the parts of the options that depend on the environment,
+// once per archive written.
+func (o *options) prepare() error {
+	if o.reproducible {
+		epoch, err := sourceDateEpoch()
+		if err != nil {
+			return err
+		}
+		o.epoch = epoch
+	}
+
+	return nil
+}
+
+// WithReproducible makes archives bit-for-bit deterministic: entries are
+// written in lexical order with normalized ownership, and modification times
+// are clamped to SOURCE_DATE_EPOCH, or zeroed when it is unset.
+func WithReproducible() Option {
+	return func(o *options) {
+		o.reproducible = true
+	}
+}
+
+// WithExclude leaves out paths matching any of the gitignore-style patterns,
+// which are relative to the source root. Excluded directories are not walked.
+func WithExclude(patterns ...string) Option {
+	return func(o *options) {
+		o.excludes = append(o.excludes, patterns...)
+	}
+}
+
+// WithInclude archives only the paths matching any of the gitignore-style
+// patterns, along with the directories leading to them.
+func WithInclude(patterns ...string) Option {
+	return func(o *options) {
+		o.includes = append(o.includes, patterns...)
+	}
+}
+
+// WithIgnoreFile reads further exclude patterns from the named file, such as
+// .cfignore, in the source root when it exists.
+func WithIgnoreFile(name string) Option {
+	return func(o *options) {
+		o.ignoreFile = name
+	}
+}
+
+// WithXattrAllow records only the extended attributes in the given
+// namespaces, such as "user" or "security". All are recorded by default.
+func WithXattrAllow(namespaces ...string) Option {
+	return func(o *options) {
+		o.xattrAllow = append(o.xattrAllow, namespaces...)
+	}
+}
+
+// WithXattrDeny never records the extended attributes in the given
+// namespaces, even when they are allowed.
+func WithXattrDeny(namespaces ...string) Option {
+	return func(o *options) {
+		o.xattrDeny = append(o.xattrDeny, namespaces...)
+	}
+}
+
+// WithUIDMap records user IDs mapped from the host into a container's user
+// namespace, so that the archive can be extracted inside it. IDs the mapping
+// does not cover are recorded as idmap.OverflowID.
+func WithUIDMap(m idmap.Mapping) Option {
+	return func(o *options) {
+		o.uidMap = m
+	}
+}
+
+// WithGIDMap records group IDs mapped from the host into a container's user
+// namespace, like WithUIDMap.
+func WithGIDMap(m idmap.Mapping) Option {
+	return func(o *options) {
+		o.gidMap = m
+	}
+}
+
+// WithOwner records every entry as owned by the given user and group.
+func WithOwner(uid, gid int) Option {
+	return func(o *options) {
+		o.forceOwner = true
+		o.uid, o.gid = uid, gid
+	}
+}
+
+// WithFsync flushes archives written to a path to stable storage before
+// they are renamed into place, and then the rename itself.
+func WithFsync() Option {
+	return func(o *options) {
+		o.fsync = true
+	}
+}
+
+func (o *options) mapsOwner() bool {
+	return o.forceOwner || o.uidMap != nil || o.gidMap != nil
+}
+
+// containerOwner returns the IDs to record for an entry owned by the host
+// IDs uid and gid.
+func (o *options) containerOwner(uid, gid int) (int, int) {
+	if o.forceOwner {
+		return o.uid, o.gid
+	}
+
+	if o.uidMap != nil {
+		uid = o.uidMap.ToContainer(uid)
+	}
+
+	if o.gidMap != nil {
+		gid = o.gidMap.ToContainer(gid)
+	}
+
+	return uid, gid
+}
+
+func (o *options) recordsXattr(name string) bool {
+	if inNamespaces(name, o.xattrDeny) {
+		return false
+	}
+
+	return len(o.xattrAllow) == 0 || inNamespaces(name, o.xattrAllow)
+}
+
+// inNamespaces reports whether the attribute name lies in any of namespaces,
+// which may also name single attributes, such as "security.capability".
+func inNamespaces(name string, namespaces []string) bool {
+	for _, namespace := range namespaces {
+		if name == namespace || strings.HasPrefix(name, namespace+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sourceDateEpoch returns the time set by SOURCE_DATE_EPOCH, or the zero time
+// when it is unset. See https://reproducible-builds.org/specs/source-date-epoch/.
+func sourceDateEpoch() (time.Time, error) {
+	value, ok := os.LookupEnv("SOURCE_DATE_EPOCH")
+	if !ok || value == "" {
+		return time.Time{}, nil
+	}
+
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH %q: %w", value, err)
+	}
+
+	return time.Unix(seconds, 0).UTC(), nil
+}
+
+func clampTime(t, epoch, floor time.Time) time.Time {
+	if epoch.IsZero() {
+		return floor
+	}
+
+	if t.After(epoch) {
+		t = epoch
+	}
+
+	if t.Before(floor) {
+		return floor
+	}
+
+	return t.UTC().Truncate(time.Second)
+}
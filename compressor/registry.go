@@ -0,0 +1,105 @@
+package compressor
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+type Factory func(opts ...Option) Compressor
+
+var ErrUnsupportedFormat = errors.New("unsupported archive type")
+
+type UnsupportedFormatError struct {
+	Dest       string
+	Extensions []string
+}
+
+func (e *UnsupportedFormatError) Error() string {
+	return fmt.Sprintf("%s is an %s: expected one of %s", e.Dest, ErrUnsupportedFormat, strings.Join(e.Extensions, ", "))
+}
+
+func (e *UnsupportedFormatError) Is(target error) bool {
+	return target == ErrUnsupportedFormat
+}
+
+var registry struct {
+	sync.RWMutex
+	formats map[string]Factory
+}
+
+func init() {
+	Register(".tgz", builtin(NewTgz))
+	Register(".tar.gz", builtin(NewTgz))
+	Register(".tar", builtin(NewTar))
+	Register(".zip", builtin(NewZip))
+}
+
+func builtin(newCompressor func(...Option) ArchiveCompressor) Factory {
+	return func(opts ...Option) Compressor {
+		return newCompressor(opts...)
+	}
+}
+
+// Register makes a format available to NewDetectable for destinations ending
+// in extension. Extensions are matched case-insensitively, longest first.
+func Register(extension string, factory Factory) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if registry.formats == nil {
+		registry.formats = map[string]Factory{}
+	}
+
+	registry.formats[normalizeExtension(extension)] = factory
+}
+
+func Unregister(extension string) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	delete(registry.formats, normalizeExtension(extension))
+}
+
+func normalizeExtension(extension string) string {
+	extension = strings.ToLower(extension)
+	if !strings.HasPrefix(extension, ".") {
+		extension = "." + extension
+	}
+
+	return extension
+}
+
+// lookupFormat calls the factory without the registry held, so that it can
+// register formats itself.
+func lookupFormat(dest string, opts []Option) (Compressor, error) {
+	factory, err := lookupFactory(dest)
+	if err != nil {
+		return nil, err
+	}
+
+	return factory(opts...), nil
+}
+
+func lookupFactory(dest string) (Factory, error) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	name := strings.ToLower(dest)
+	match := ""
+	for extension := range registry.formats {
+		if strings.HasSuffix(name, extension) && len(extension) > len(match) {
+			match = extension
+		}
+	}
+
+	if match == "" {
+		extensions := slices.Sorted(maps.Keys(registry.formats))
+		return nil, &UnsupportedFormatError{Dest: dest, Extensions: extensions}
+	}
+
+	return registry.formats[match], nil
+}
@@ -0,0 +1,16 @@
+//go:build unix
+
+package compressor
+
+import "os"
+
+// syncDir makes a rename within dir durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
@@ -0,0 +1,7 @@
+//go:build windows
+
+package compressor
+
+func syncDir(_ string) error {
+	return nil
+}
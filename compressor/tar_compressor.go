@@ -0,0 +1,30 @@
+package compressor
+
+import (
+	"context"
+	"io"
+)
+
+func NewTar(opts ...Option) ArchiveCompressor {
+	return &tarCompressor{opts: opts}
+}
+
+type tarCompressor struct {
+	opts []Option
+}
+
+func (compressor *tarCompressor) Compress(src string, dest string) error {
+	return compressor.CompressContext(context.Background(), src, dest)
+}
+
+func (compressor *tarCompressor) CompressContext(ctx context.Context, src string, dest string) error {
+	return compressToFile(ctx, compressor, src, dest, compressor.opts)
+}
+
+func (compressor *tarCompressor) CompressTo(src string, w io.Writer) error {
+	return compressor.CompressToContext(context.Background(), src, w)
+}
+
+func (compressor *tarCompressor) CompressToContext(ctx context.Context, src string, w io.Writer) error {
+	return WriteTarContext(ctx, src, w, compressor.opts...)
+}
@@ -0,0 +1,78 @@
+package compressor_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "code.cloudfoundry.org/archiver/compressor"
+	"code.cloudfoundry.org/archiver/extractor"
+)
+
+var _ = Describe("Tar Compressor", func() {
+	var compressor ArchiveCompressor
+	var destDir string
+	var extracticator extractor.ArchiveExtractor
+	var victimDir string
+
+	BeforeEach(func() {
+		var err error
+
+		compressor = NewTar()
+		extracticator = extractor.NewTar()
+
+		destDir, err = os.MkdirTemp("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		victimDir, err = os.MkdirTemp("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		err = os.Mkdir(filepath.Join(victimDir, "empty"), 0755)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = os.WriteFile(filepath.Join(victimDir, "some_file"), []byte("stuff"), 0644)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(destDir)
+		os.RemoveAll(victimDir)
+	})
+
+	It("compresses the src path recursively to dest file", func() {
+		destFile := filepath.Join(destDir, "compress-dst.tar")
+
+		err := compressor.Compress(victimDir+"/", destFile)
+		Expect(err).NotTo(HaveOccurred())
+
+		finalReadingDir, err := os.MkdirTemp(destDir, "final")
+		Expect(err).NotTo(HaveOccurred())
+
+		err = extracticator.Extract(destFile, finalReadingDir)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(retrieveFilePaths(finalReadingDir)).To(Equal(retrieveFilePaths(victimDir)))
+
+		contents, err := os.ReadFile(filepath.Join(finalReadingDir, "some_file"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(contents)).To(Equal("stuff"))
+	})
+
+	It("compresses the src path to a writer", func() {
+		buffer := new(bytes.Buffer)
+
+		err := compressor.CompressTo(victimDir+"/", buffer)
+		Expect(err).NotTo(HaveOccurred())
+
+		finalReadingDir, err := os.MkdirTemp(destDir, "final")
+		Expect(err).NotTo(HaveOccurred())
+
+		err = extracticator.ExtractReader(buffer, finalReadingDir)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(retrieveFilePaths(finalReadingDir)).To(Equal(retrieveFilePaths(victimDir)))
+	})
+})
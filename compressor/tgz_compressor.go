@@ -2,28 +2,42 @@ package compressor
 
 import (
 	"compress/gzip"
-	"os"
+	"context"
+	"io"
 )
 
-type Compressor interface {
-	Compress(src string, dst string) error
+func NewTgz(opts ...Option) ArchiveCompressor {
+	return &tgzCompressor{opts: opts}
 }
 
-func NewTgz() Compressor {
-	return &tgzCompressor{}
+type tgzCompressor struct {
+	opts []Option
 }
 
-type tgzCompressor struct{}
-
 func (compressor *tgzCompressor) Compress(src string, dest string) error {
-	fw, err := os.Create(dest)
+	return compressor.CompressContext(context.Background(), src, dest)
+}
+
+func (compressor *tgzCompressor) CompressContext(ctx context.Context, src string, dest string) error {
+	return compressToFile(ctx, compressor, src, dest, compressor.opts)
+}
+
+func (compressor *tgzCompressor) CompressTo(src string, w io.Writer) error {
+	return compressor.CompressToContext(context.Background(), src, w)
+}
+
+func (compressor *tgzCompressor) CompressToContext(ctx context.Context, src string, w io.Writer) error {
+	gw := gzip.NewWriter(w)
+	if newOptions(compressor.opts).reproducible {
+		// no name, no modification time, unknown OS
+		gw.Header = gzip.Header{OS: 255}
+	}
+
+	err := WriteTarContext(ctx, src, gw, compressor.opts...)
 	if err != nil {
+		gw.Close()
 		return err
 	}
-	defer fw.Close()
-
-	gw := gzip.NewWriter(fw)
-	defer gw.Close()
 
-	return WriteTar(src, gw)
+	return gw.Close()
 }
@@ -1,8 +1,13 @@
 package compressor_test
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
+	"runtime"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -27,9 +32,9 @@ func retrieveFilePaths(dir string) (results []string) {
 }
 
 var _ = Describe("Tgz Compressor", func() {
-	var compressor Compressor
+	var compressor ArchiveCompressor
 	var destDir string
-	var extracticator extractor.Extractor
+	var extracticator extractor.ArchiveExtractor
 	var victimFile *os.File
 	var victimDir string
 
@@ -117,4 +122,120 @@ var _ = Describe("Tgz Compressor", func() {
 
 		Expect(emptyDirInfo.IsDir()).To(BeTrue())
 	})
+
+	It("removes the dest file and returns the context's error when cancelled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		destFile := filepath.Join(destDir, "compress-dst.tgz")
+
+		err := compressor.CompressContext(ctx, victimDir, destFile)
+		Expect(err).To(MatchError(context.Canceled))
+		Expect(destFile).NotTo(BeAnExistingFile())
+	})
+
+	It("compresses the src path to a writer", func() {
+		buffer := new(bytes.Buffer)
+
+		err := compressor.CompressTo(victimDir+"/", buffer)
+		Expect(err).NotTo(HaveOccurred())
+
+		finalReadingDir, err := os.MkdirTemp(destDir, "final")
+		Expect(err).NotTo(HaveOccurred())
+
+		err = extracticator.ExtractReader(buffer, finalReadingDir)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(retrieveFilePaths(finalReadingDir)).To(Equal(retrieveFilePaths(victimDir)))
+	})
+
+	It("writes identical archives in reproducible mode", func() {
+		compressor = NewTgz(WithReproducible())
+
+		first := new(bytes.Buffer)
+		Expect(compressor.CompressTo(victimDir, first)).To(Succeed())
+
+		later := time.Now().Add(time.Hour)
+		Expect(os.Chtimes(filepath.Join(victimDir, "not_empty", "some_file"), later, later)).To(Succeed())
+
+		second := new(bytes.Buffer)
+		Expect(compressor.CompressTo(victimDir, second)).To(Succeed())
+
+		Expect(second.Bytes()).To(Equal(first.Bytes()))
+	})
+
+	It("renames a complete archive into place, leaving no temporary files", func() {
+		destFile := filepath.Join(destDir, "compress-dst.tgz")
+
+		err := NewTgz(WithFsync()).Compress(victimDir, destFile)
+		Expect(err).NotTo(HaveOccurred())
+
+		entries, err := os.ReadDir(destDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0].Name()).To(Equal("compress-dst.tgz"))
+
+		finalReadingDir, err := os.MkdirTemp(destDir, "final")
+		Expect(err).NotTo(HaveOccurred())
+
+		err = extracticator.Extract(destFile, finalReadingDir)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("leaves an existing dest file untouched when compression fails", func() {
+		destFile := filepath.Join(destDir, "compress-dst.tgz")
+		Expect(os.WriteFile(destFile, []byte("previous-archive"), 0644)).To(Succeed())
+
+		err := compressor.Compress(filepath.Join(victimDir, "missing"), destFile)
+		Expect(err).To(HaveOccurred())
+
+		contents, err := os.ReadFile(destFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(contents)).To(Equal("previous-archive"))
+
+		entries, err := os.ReadDir(destDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+	})
+
+	It("keeps the permissions of an existing dest file", func() {
+		if runtime.GOOS == "windows" {
+			Skip("Windows has no unix permissions")
+		}
+
+		destFile := filepath.Join(destDir, "compress-dst.tgz")
+		Expect(os.WriteFile(destFile, []byte("previous-archive"), 0600)).To(Succeed())
+
+		err := compressor.Compress(victimDir, destFile)
+		Expect(err).NotTo(HaveOccurred())
+
+		info, err := os.Stat(destFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+	})
+
+	It("returns the error from finishing the archive", func() {
+		complete := new(bytes.Buffer)
+		Expect(compressor.CompressTo(victimDir, complete)).To(Succeed())
+
+		err := compressor.CompressTo(victimDir, &failingWriter{limit: complete.Len() - 1})
+		Expect(err).To(MatchError(errDiskFull))
+	})
 })
+
+var errDiskFull = errors.New("no space left on device")
+
+// failingWriter accepts limit bytes and fails every write after them.
+type failingWriter struct {
+	limit   int
+	written int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.written+len(p) > w.limit {
+		return 0, errDiskFull
+	}
+
+	w.written += len(p)
+	return len(p), nil
+}
@@ -0,0 +1,135 @@
+package compressor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type sourceEntry struct {
+	path string
+	name string
+	info os.FileInfo
+}
+
+func walkSource(ctx context.Context, srcPath string, o *options, visit func(path, name string, info os.FileInfo) error) error {
+	absPath, err := filepath.Abs(srcPath)
+	if err != nil {
+		return err
+	}
+
+	rootInfo, err := os.Lstat(absPath)
+	if err != nil {
+		return err
+	}
+
+	patternRoot := absPath
+	if !rootInfo.IsDir() {
+		patternRoot = filepath.Dir(absPath)
+	}
+
+	excludes, includes, err := o.patterns(absPath, rootInfo.IsDir())
+	if err != nil {
+		return err
+	}
+
+	// with include patterns, directories are only archived once something
+	// inside them is
+	var pending []sourceEntry
+
+	return filepath.Walk(absPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var relative string
+		if os.IsPathSeparator(srcPath[len(srcPath)-1]) {
+			relative, err = filepath.Rel(absPath, path)
+		} else {
+			relative, err = filepath.Rel(filepath.Dir(absPath), path)
+		}
+
+		relative = filepath.ToSlash(relative)
+
+		if err != nil {
+			return err
+		}
+
+		if path != absPath || !info.IsDir() {
+			matchName, err := filepath.Rel(patternRoot, path)
+			if err != nil {
+				return err
+			}
+			matchName = filepath.ToSlash(matchName)
+
+			if excludes.matches(matchName, info.IsDir()) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if includes != nil && !includes.matchesWithin(matchName, info.IsDir()) {
+				if info.IsDir() {
+					pending = append(pending, sourceEntry{path: path, name: relative, info: info})
+				}
+				return nil
+			}
+		}
+
+		for _, dir := range pending {
+			if strings.HasPrefix(path, dir.path+string(filepath.Separator)) {
+				if err := visit(dir.path, dir.name, dir.info); err != nil {
+					return err
+				}
+			}
+		}
+		pending = pending[:0]
+
+		return visit(path, relative, info)
+	})
+}
+
+func (o *options) patterns(root string, rootIsDir bool) (excludes, includes patternList, err error) {
+	excludeLines := o.excludes
+	if o.ignoreFile != "" && rootIsDir {
+		lines, err := readPatternFile(filepath.Join(root, o.ignoreFile))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, nil, err
+		}
+		excludeLines = append(excludeLines[:len(excludeLines):len(excludeLines)], lines...)
+	}
+
+	excludes, err = compilePatterns(excludeLines)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(o.includes) > 0 {
+		includes, err = compilePatterns(o.includes)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return excludes, includes, nil
+}
+
+func archiveName(path, name string, fi os.FileInfo) string {
+	if fi.IsDir() && !os.IsPathSeparator(name[len(name)-1]) {
+		name = name + "/"
+	}
+
+	if fi.Mode().IsRegular() && name == "." {
+		// archiving a single file
+		return filepath.ToSlash(filepath.Base(path))
+	}
+
+	return filepath.ToSlash(name)
+}
@@ -2,45 +2,53 @@ package compressor
 
 import (
 	"archive/tar"
+	"context"
 	"io"
 	"os"
-	"path/filepath"
+	"time"
 )
 
-func WriteTar(srcPath string, dest io.Writer) error {
-	absPath, err := filepath.Abs(srcPath)
-	if err != nil {
+const paxSchilyXattr = "SCHILY.xattr."
+
+func WriteTar(srcPath string, dest io.Writer, opts ...Option) error {
+	return WriteTarContext(context.Background(), srcPath, dest, opts...)
+}
+
+func WriteTarContext(ctx context.Context, srcPath string, dest io.Writer, opts ...Option) error {
+	o := newOptions(opts)
+	if err := o.prepare(); err != nil {
 		return err
 	}
 
 	tw := tar.NewWriter(dest)
-	defer tw.Close()
 
-	err = filepath.Walk(absPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		var relative string
-		if os.IsPathSeparator(srcPath[len(srcPath)-1]) {
-			relative, err = filepath.Rel(absPath, path)
-		} else {
-			relative, err = filepath.Rel(filepath.Dir(absPath), path)
-		}
+	w := &tarWriter{
+		ctx:   ctx,
+		opts:  o,
+		tw:    tw,
+		links: map[inode]string{},
+	}
 
-		relative = filepath.ToSlash(relative)
+	err := walkSource(ctx, srcPath, o, func(path, name string, _ os.FileInfo) error {
+		return w.addTarFile(path, name)
+	})
+	if err != nil {
+		return err
+	}
 
-		if err != nil {
-			return err
-		}
+	return tw.Close()
+}
 
-		return addTarFile(path, relative, tw)
-	})
+type tarWriter struct {
+	ctx  context.Context
+	opts *options
+	tw   *tar.Writer
 
-	return err
+	// links maps hard-linked files to the name they were first archived as
+	links map[inode]string
 }
 
-func addTarFile(path, name string, tw *tar.Writer) error {
+func (w *tarWriter) addTarFile(path, name string) error {
 	fi, err := os.Lstat(path)
 	if err != nil {
 		return err
@@ -58,18 +66,42 @@ func addTarFile(path, name string, tw *tar.Writer) error {
 		return err
 	}
 
-	if fi.IsDir() && !os.IsPathSeparator(name[len(name)-1]) {
-		name = name + "/"
+	hdr.Name = archiveName(path, name, fi)
+
+	if hdr.Typeflag == tar.TypeReg {
+		if id, ok := hardLinkID(fi); ok {
+			if first, seen := w.links[id]; seen {
+				hdr.Typeflag = tar.TypeLink
+				hdr.Linkname = first
+				hdr.Size = 0
+			} else {
+				w.links[id] = hdr.Name
+			}
+		}
+	}
+
+	if hdr.Typeflag != tar.TypeLink {
+		err = w.addXattrs(path, hdr)
+		if err != nil {
+			return err
+		}
+	}
+
+	if w.opts.reproducible {
+		hdr.ModTime = clampTime(hdr.ModTime, w.opts.epoch, time.Unix(0, 0))
+		hdr.AccessTime = time.Time{}
+		hdr.ChangeTime = time.Time{}
+		hdr.Uid, hdr.Gid = 0, 0
+		hdr.Uname, hdr.Gname = "", ""
 	}
 
-	if hdr.Typeflag == tar.TypeReg && name == "." {
-		// archiving a single file
-		hdr.Name = filepath.ToSlash(filepath.Base(path))
-	} else {
-		hdr.Name = filepath.ToSlash(name)
+	if w.opts.mapsOwner() {
+		// host user and group names mean nothing inside the container
+		hdr.Uid, hdr.Gid = w.opts.containerOwner(hdr.Uid, hdr.Gid)
+		hdr.Uname, hdr.Gname = "", ""
 	}
 
-	if err := tw.WriteHeader(hdr); err != nil {
+	if err := w.tw.WriteHeader(hdr); err != nil {
 		return err
 	}
 
@@ -81,7 +113,7 @@ func addTarFile(path, name string, tw *tar.Writer) error {
 
 		defer file.Close()
 
-		_, err = io.Copy(tw, file)
+		_, err = io.Copy(w.tw, &contextReader{ctx: w.ctx, r: file})
 		if err != nil {
 			return err
 		}
@@ -89,3 +121,23 @@ func addTarFile(path, name string, tw *tar.Writer) error {
 
 	return nil
 }
+
+func (w *tarWriter) addXattrs(path string, hdr *tar.Header) error {
+	xattrs, err := readXattrs(path)
+	if err != nil {
+		return err
+	}
+
+	for name, value := range xattrs {
+		if !w.opts.recordsXattr(name) {
+			continue
+		}
+
+		if hdr.PAXRecords == nil {
+			hdr.PAXRecords = map[string]string{}
+		}
+		hdr.PAXRecords[paxSchilyXattr+name] = value
+	}
+
+	return nil
+}
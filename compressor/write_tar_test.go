@@ -3,14 +3,17 @@ package compressor_test
 import (
 	"archive/tar"
 	"bytes"
+	"context"
 	"io"
 	"os"
 	"path/filepath"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
 	. "code.cloudfoundry.org/archiver/compressor"
+	"code.cloudfoundry.org/archiver/idmap"
 )
 
 var _ = Describe("WriteTar", func() {
@@ -76,6 +79,39 @@ var _ = Describe("WriteTar", func() {
 		Expect(header.Linkname).To(Equal("some-file"))
 	})
 
+	Context("with hard links", func() {
+		BeforeEach(func() {
+			someFile := filepath.Join(srcPath, "inner-dir", "some-file")
+			err := os.Link(someFile, filepath.Join(srcPath, "inner-dir", "some-hard-link"))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("archives the file once and links to it", func() {
+			Expect(writeErr).NotTo(HaveOccurred())
+
+			reader := tar.NewReader(buffer)
+
+			headers := map[string]*tar.Header{}
+			for {
+				header, err := reader.Next()
+				if err == io.EOF {
+					break
+				}
+				Expect(err).NotTo(HaveOccurred())
+
+				headers[header.Name] = header
+			}
+
+			Expect(headers["outer-dir/inner-dir/some-file"].Typeflag).To(Equal(byte(tar.TypeReg)))
+			Expect(headers["outer-dir/inner-dir/some-file"].Size).To(Equal(int64(3)))
+
+			link := headers["outer-dir/inner-dir/some-hard-link"]
+			Expect(link.Typeflag).To(Equal(byte(tar.TypeLink)))
+			Expect(link.Linkname).To(Equal("outer-dir/inner-dir/some-file"))
+			Expect(link.Size).To(BeZero())
+		})
+	})
+
 	Context("with a trailing slash", func() {
 		BeforeEach(func() {
 			srcPath = srcPath + "/"
@@ -143,4 +179,244 @@ var _ = Describe("WriteTar", func() {
 			Expect(writeErr).To(BeAssignableToTypeOf(&os.PathError{}))
 		})
 	})
+
+	Context("when the context is cancelled", func() {
+		It("returns the context's error", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			err := WriteTarContext(ctx, srcPath, new(bytes.Buffer))
+			Expect(err).To(MatchError(context.Canceled))
+		})
+	})
+
+	Context("in reproducible mode", func() {
+		var opts []Option
+
+		BeforeEach(func() {
+			opts = []Option{WithReproducible()}
+		})
+
+		It("zeroes modification times and ownership", func() {
+			reproducible := new(bytes.Buffer)
+			Expect(WriteTar(srcPath, reproducible, opts...)).To(Succeed())
+
+			reader := tar.NewReader(reproducible)
+			for {
+				header, err := reader.Next()
+				if err == io.EOF {
+					break
+				}
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(header.ModTime.Unix()).To(BeZero())
+				Expect(header.Uid).To(BeZero())
+				Expect(header.Gid).To(BeZero())
+				Expect(header.Uname).To(BeEmpty())
+				Expect(header.Gname).To(BeEmpty())
+			}
+		})
+
+		It("writes the same bytes when the tree's times change", func() {
+			first := new(bytes.Buffer)
+			Expect(WriteTar(srcPath, first, opts...)).To(Succeed())
+
+			later := time.Now().Add(time.Hour)
+			someFile := filepath.Join(srcPath, "inner-dir", "some-file")
+			Expect(os.Chtimes(someFile, later, later)).To(Succeed())
+
+			second := new(bytes.Buffer)
+			Expect(WriteTar(srcPath, second, opts...)).To(Succeed())
+
+			Expect(second.Bytes()).To(Equal(first.Bytes()))
+		})
+
+		Context("when SOURCE_DATE_EPOCH is set", func() {
+			var epoch time.Time
+
+			BeforeEach(func() {
+				epoch = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+				GinkgoT().Setenv("SOURCE_DATE_EPOCH", "1577836800")
+
+				older := epoch.Add(-time.Hour)
+				Expect(os.Chtimes(filepath.Join(srcPath, "inner-dir", "some-file"), older, older)).To(Succeed())
+			})
+
+			It("clamps modification times to it", func() {
+				reproducible := new(bytes.Buffer)
+				Expect(WriteTar(srcPath, reproducible, opts...)).To(Succeed())
+
+				reader := tar.NewReader(reproducible)
+				for {
+					header, err := reader.Next()
+					if err == io.EOF {
+						break
+					}
+					Expect(err).NotTo(HaveOccurred())
+
+					if header.Name == "outer-dir/inner-dir/some-file" {
+						Expect(header.ModTime.Unix()).To(Equal(epoch.Add(-time.Hour).Unix()))
+					} else {
+						Expect(header.ModTime.Unix()).To(Equal(epoch.Unix()))
+					}
+				}
+			})
+		})
+
+		Context("when SOURCE_DATE_EPOCH is invalid", func() {
+			BeforeEach(func() {
+				GinkgoT().Setenv("SOURCE_DATE_EPOCH", "yesterday")
+			})
+
+			It("returns an error", func() {
+				Expect(WriteTar(srcPath, new(bytes.Buffer), opts...)).To(MatchError(ContainSubstring("SOURCE_DATE_EPOCH")))
+			})
+		})
+	})
+
+	Context("with patterns", func() {
+		var opts []Option
+
+		tarNames := func(buffer *bytes.Buffer) []string {
+			var names []string
+
+			reader := tar.NewReader(buffer)
+			for {
+				header, err := reader.Next()
+				if err == io.EOF {
+					return names
+				}
+				Expect(err).NotTo(HaveOccurred())
+
+				names = append(names, header.Name)
+			}
+		}
+
+		BeforeEach(func() {
+			opts = nil
+
+			for _, dir := range []string{".git", "node_modules/.cache", "node_modules/left-pad", "build", "docs"} {
+				Expect(os.MkdirAll(filepath.Join(srcPath, dir), 0755)).To(Succeed())
+			}
+
+			for _, file := range []string{".git/HEAD", "node_modules/.cache/blob", "node_modules/left-pad/index.js", "build/app.log", "docs/readme.md", "debug.log", "keep.log"} {
+				Expect(os.WriteFile(filepath.Join(srcPath, file), []byte("contents"), 0644)).To(Succeed())
+			}
+		})
+
+		archive := func() []string {
+			archived := new(bytes.Buffer)
+			Expect(WriteTar(srcPath+"/", archived, opts...)).To(Succeed())
+			return tarNames(archived)
+		}
+
+		It("leaves out excluded paths", func() {
+			opts = []Option{WithExclude(".git", "node_modules/.cache", "*.log", "!keep.log", "build/")}
+
+			Expect(archive()).To(Equal([]string{
+				"./",
+				"docs/",
+				"docs/readme.md",
+				"inner-dir/",
+				"inner-dir/some-file",
+				"inner-dir/some-symlink",
+				"keep.log",
+				"node_modules/",
+				"node_modules/left-pad/",
+				"node_modules/left-pad/index.js",
+			}))
+		})
+
+		It("does not walk excluded directories", func() {
+			Expect(os.Chmod(filepath.Join(srcPath, ".git"), 0)).To(Succeed())
+			DeferCleanup(os.Chmod, filepath.Join(srcPath, ".git"), os.FileMode(0755))
+
+			opts = []Option{WithExclude("/.git/")}
+
+			Expect(archive()).NotTo(ContainElement(HavePrefix(".git")))
+		})
+
+		It("reads exclude patterns from the ignore file in the source root", func() {
+			ignore := "# build output\n/build\n*.log\n\nnode_modules/**\n.cfignore\n"
+			Expect(os.WriteFile(filepath.Join(srcPath, ".cfignore"), []byte(ignore), 0644)).To(Succeed())
+
+			opts = []Option{WithIgnoreFile(".cfignore"), WithExclude(".git")}
+
+			Expect(archive()).To(Equal([]string{
+				"./",
+				"docs/",
+				"docs/readme.md",
+				"inner-dir/",
+				"inner-dir/some-file",
+				"inner-dir/some-symlink",
+				"node_modules/",
+			}))
+		})
+
+		It("ignores a missing ignore file", func() {
+			opts = []Option{WithIgnoreFile(".cfignore")}
+
+			Expect(archive()).To(ContainElement("build/app.log"))
+		})
+
+		It("archives only included paths and the directories leading to them", func() {
+			opts = []Option{WithInclude("*.js", "docs/"), WithExclude(".git")}
+
+			Expect(archive()).To(Equal([]string{
+				"./",
+				"docs/",
+				"docs/readme.md",
+				"node_modules/",
+				"node_modules/left-pad/",
+				"node_modules/left-pad/index.js",
+			}))
+		})
+	})
+
+	Context("with an ownership mapping", func() {
+		owners := func(opts ...Option) map[string][2]int {
+			archive := new(bytes.Buffer)
+			Expect(WriteTar(srcPath, archive, opts...)).To(Succeed())
+
+			owners := map[string][2]int{}
+			reader := tar.NewReader(archive)
+			for {
+				header, err := reader.Next()
+				if err == io.EOF {
+					break
+				}
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(header.Uname).To(BeEmpty())
+				Expect(header.Gname).To(BeEmpty())
+				owners[header.Name] = [2]int{header.Uid, header.Gid}
+			}
+
+			return owners
+		}
+
+		It("records host IDs as the container IDs they map to", func() {
+			uidMap := idmap.Mapping{{ContainerID: 1000, HostID: os.Getuid(), Size: 1}}
+			gidMap := idmap.Mapping{{ContainerID: 1001, HostID: os.Getgid(), Size: 1}}
+
+			Expect(owners(WithUIDMap(uidMap), WithGIDMap(gidMap))).To(HaveEach([2]int{1000, 1001}))
+		})
+
+		It("records IDs the mapping does not cover as the overflow ID", func() {
+			mapping := idmap.Mapping{{ContainerID: 0, HostID: os.Getuid() + 1, Size: 1}}
+
+			Expect(owners(WithUIDMap(mapping))).To(HaveEach([2]int{idmap.OverflowID, os.Getgid()}))
+		})
+
+		It("can record every entry as owned by the same user", func() {
+			Expect(owners(WithOwner(4000, 4001))).To(HaveEach([2]int{4000, 4001}))
+		})
+	})
+
+	It("returns the error from finishing the archive", func() {
+		Expect(writeErr).NotTo(HaveOccurred())
+
+		err := WriteTar(srcPath, &failingWriter{limit: buffer.Len() - 1})
+		Expect(err).To(MatchError(errDiskFull))
+	})
 })
@@ -0,0 +1,91 @@
+package compressor
+
+import (
+	"archive/zip"
+	"context"
+	"io"
+	"os"
+	"time"
+)
+
+// zipEpoch is the earliest time an MS-DOS timestamp can represent.
+var zipEpoch = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func WriteZip(srcPath string, dest io.Writer, opts ...Option) error {
+	return WriteZipContext(context.Background(), srcPath, dest, opts...)
+}
+
+func WriteZipContext(ctx context.Context, srcPath string, dest io.Writer, opts ...Option) error {
+	o := newOptions(opts)
+	if err := o.prepare(); err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(dest)
+
+	err := walkSource(ctx, srcPath, o, func(path, name string, _ os.FileInfo) error {
+		return addZipFile(ctx, o, path, name, zw)
+	})
+	if err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+func addZipFile(ctx context.Context, o *options, path, name string, zw *zip.Writer) error {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+
+	name = archiveName(path, name, fi)
+	if name == "./" {
+		// the root of a trailing-slash source is implied by the zip itself
+		return nil
+	}
+
+	hdr, err := zip.FileInfoHeader(fi)
+	if err != nil {
+		return err
+	}
+
+	hdr.Name = name
+	if fi.Mode().IsRegular() {
+		hdr.Method = zip.Deflate
+	} else {
+		hdr.Method = zip.Store
+	}
+
+	if o.reproducible {
+		hdr.Modified = clampTime(hdr.Modified, o.epoch, zipEpoch)
+	}
+
+	w, err := zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case fi.Mode()&os.ModeSymlink != 0:
+		link, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.WriteString(w, link)
+		return err
+	case fi.Mode().IsRegular():
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+
+		defer file.Close()
+
+		_, err = io.Copy(w, &contextReader{ctx: ctx, r: file})
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package compressor_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "code.cloudfoundry.org/archiver/compressor"
+)
+
+var _ = Describe("WriteZip", func() {
+	var srcPath string
+	var buffer *bytes.Buffer
+	var writeErr error
+
+	BeforeEach(func() {
+		dir, err := os.MkdirTemp("", "archive-dir")
+		Expect(err).NotTo(HaveOccurred())
+
+		err = os.Mkdir(filepath.Join(dir, "outer-dir"), 0755)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = os.Mkdir(filepath.Join(dir, "outer-dir", "inner-dir"), 0755)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = os.Mkdir(filepath.Join(dir, "outer-dir", "empty-dir"), 0755)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = os.WriteFile(filepath.Join(dir, "outer-dir", "inner-dir", "some-file"), []byte("sup"), 0644)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = os.Symlink("some-file", filepath.Join(dir, "outer-dir", "inner-dir", "some-symlink"))
+		Expect(err).NotTo(HaveOccurred())
+
+		srcPath = filepath.Join(dir, "outer-dir")
+		buffer = new(bytes.Buffer)
+	})
+
+	JustBeforeEach(func() {
+		writeErr = WriteZip(srcPath, buffer)
+	})
+
+	readZip := func() []*zip.File {
+		reader, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+		Expect(err).NotTo(HaveOccurred())
+		return reader.File
+	}
+
+	readBody := func(file *zip.File) string {
+		rc, err := file.Open()
+		Expect(err).NotTo(HaveOccurred())
+		defer rc.Close()
+
+		contents, err := io.ReadAll(rc)
+		Expect(err).NotTo(HaveOccurred())
+		return string(contents)
+	}
+
+	It("writes a .zip stream", func() {
+		Expect(writeErr).NotTo(HaveOccurred())
+
+		files := readZip()
+		Expect(files).To(HaveLen(5))
+
+		Expect(files[0].Name).To(Equal("outer-dir/"))
+		Expect(files[0].FileInfo().IsDir()).To(BeTrue())
+
+		Expect(files[1].Name).To(Equal("outer-dir/empty-dir/"))
+		Expect(files[1].FileInfo().IsDir()).To(BeTrue())
+
+		Expect(files[2].Name).To(Equal("outer-dir/inner-dir/"))
+		Expect(files[2].FileInfo().IsDir()).To(BeTrue())
+
+		Expect(files[3].Name).To(Equal("outer-dir/inner-dir/some-file"))
+		Expect(files[3].FileInfo().IsDir()).To(BeFalse())
+		Expect(files[3].Method).To(Equal(zip.Deflate))
+		Expect(readBody(files[3])).To(Equal("sup"))
+
+		Expect(files[4].Name).To(Equal("outer-dir/inner-dir/some-symlink"))
+		Expect(files[4].Mode() & os.ModeSymlink).To(Equal(os.ModeSymlink))
+		Expect(readBody(files[4])).To(Equal("some-file"))
+	})
+
+	Context("with a trailing slash", func() {
+		BeforeEach(func() {
+			srcPath = srcPath + "/"
+		})
+
+		It("archives the directory's contents", func() {
+			Expect(writeErr).NotTo(HaveOccurred())
+
+			files := readZip()
+			Expect(files).To(HaveLen(4))
+
+			Expect(files[0].Name).To(Equal("empty-dir/"))
+			Expect(files[1].Name).To(Equal("inner-dir/"))
+			Expect(files[2].Name).To(Equal("inner-dir/some-file"))
+			Expect(readBody(files[2])).To(Equal("sup"))
+			Expect(files[3].Name).To(Equal("inner-dir/some-symlink"))
+		})
+	})
+
+	Context("with a single file", func() {
+		BeforeEach(func() {
+			srcPath = filepath.Join(srcPath, "inner-dir", "some-file")
+		})
+
+		It("archives the single file at the root", func() {
+			Expect(writeErr).NotTo(HaveOccurred())
+
+			files := readZip()
+			Expect(files).To(HaveLen(1))
+			Expect(files[0].Name).To(Equal("some-file"))
+			Expect(readBody(files[0])).To(Equal("sup"))
+		})
+	})
+
+	Context("when there is no file at the given path", func() {
+		BeforeEach(func() {
+			srcPath = filepath.Join(srcPath, "barf")
+		})
+
+		It("returns an error", func() {
+			Expect(writeErr).To(BeAssignableToTypeOf(&os.PathError{}))
+		})
+	})
+
+	It("returns the error from finishing the archive", func() {
+		Expect(writeErr).NotTo(HaveOccurred())
+
+		err := WriteZip(srcPath, &failingWriter{limit: buffer.Len() - 1})
+		Expect(err).To(MatchError(errDiskFull))
+	})
+})
@@ -0,0 +1,8 @@
+//go:build darwin || freebsd || netbsd
+
+package compressor
+
+import "golang.org/x/sys/unix"
+
+// errNoXattr reports an attribute that is not set.
+const errNoXattr = unix.ENOATTR
@@ -0,0 +1,6 @@
+package compressor
+
+import "golang.org/x/sys/unix"
+
+// errNoXattr reports an attribute that is not set.
+const errNoXattr = unix.ENODATA
@@ -0,0 +1,7 @@
+//go:build !linux && !darwin && !freebsd && !netbsd
+
+package compressor
+
+func readXattrs(_ string) (map[string]string, error) {
+	return nil, nil
+}
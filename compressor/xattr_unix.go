@@ -0,0 +1,85 @@
+//go:build linux || darwin || freebsd || netbsd
+
+package compressor
+
+import (
+	"bytes"
+	"errors"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+func readXattrs(path string) (map[string]string, error) {
+	names, err := listXattrs(path)
+	if err != nil {
+		if errors.Is(err, syscall.ENOTSUP) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	xattrs := map[string]string{}
+	for _, name := range names {
+		value, err := getXattr(path, name)
+		if err != nil {
+			if errors.Is(err, errNoXattr) {
+				// removed since it was listed
+				continue
+			}
+			return nil, err
+		}
+
+		xattrs[name] = string(value)
+	}
+
+	return xattrs, nil
+}
+
+func listXattrs(path string) ([]string, error) {
+	for {
+		size, err := unix.Llistxattr(path, nil)
+		if err != nil || size == 0 {
+			return nil, err
+		}
+
+		buf := make([]byte, size)
+		size, err = unix.Llistxattr(path, buf)
+		if errors.Is(err, syscall.ERANGE) {
+			// grew since it was sized
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var names []string
+		for _, name := range bytes.Split(buf[:size], []byte{0}) {
+			if len(name) > 0 {
+				names = append(names, string(name))
+			}
+		}
+
+		return names, nil
+	}
+}
+
+func getXattr(path, name string) ([]byte, error) {
+	for {
+		size, err := unix.Lgetxattr(path, name, nil)
+		if err != nil || size == 0 {
+			return nil, err
+		}
+
+		buf := make([]byte, size)
+		size, err = unix.Lgetxattr(path, name, buf)
+		if errors.Is(err, syscall.ERANGE) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return buf[:size], nil
+	}
+}
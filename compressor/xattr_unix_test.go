@@ -0,0 +1,91 @@
+//go:build linux || darwin || freebsd || netbsd
+
+package compressor_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/sys/unix"
+
+	. "code.cloudfoundry.org/archiver/compressor"
+	"code.cloudfoundry.org/archiver/extractor"
+)
+
+var _ = Describe("Extended attributes", func() {
+	var srcDir string
+	var someFile string
+
+	BeforeEach(func() {
+		var err error
+
+		srcDir, err = os.MkdirTemp("", "xattr-src")
+		Expect(err).NotTo(HaveOccurred())
+
+		someFile = filepath.Join(srcDir, "some-file")
+		Expect(os.WriteFile(someFile, []byte("sup"), 0644)).To(Succeed())
+
+		err = unix.Lsetxattr(someFile, "user.archiver", []byte("some-value"), 0)
+		if errors.Is(err, syscall.ENOTSUP) {
+			Skip("the filesystem does not support user extended attributes")
+		}
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(srcDir)
+	})
+
+	paxRecords := func(opts ...Option) map[string]string {
+		buffer := new(bytes.Buffer)
+		Expect(WriteTar(srcDir+"/", buffer, opts...)).To(Succeed())
+
+		reader := tar.NewReader(buffer)
+		for {
+			header, err := reader.Next()
+			if err == io.EOF {
+				Fail("some-file was not archived")
+			}
+			Expect(err).NotTo(HaveOccurred())
+
+			if header.Name == "some-file" {
+				return header.PAXRecords
+			}
+		}
+	}
+
+	It("records them as PAX records", func() {
+		Expect(paxRecords()).To(HaveKeyWithValue("SCHILY.xattr.user.archiver", "some-value"))
+	})
+
+	It("leaves out denied namespaces", func() {
+		Expect(paxRecords(WithXattrDeny("user"))).NotTo(HaveKey("SCHILY.xattr.user.archiver"))
+	})
+
+	It("records only allowed namespaces", func() {
+		Expect(paxRecords(WithXattrAllow("trusted"))).NotTo(HaveKey("SCHILY.xattr.user.archiver"))
+		Expect(paxRecords(WithXattrAllow("user.archiver"))).To(HaveKey("SCHILY.xattr.user.archiver"))
+	})
+
+	It("round-trips them through the extractor", func() {
+		destDir, err := os.MkdirTemp("", "xattr-dest")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(destDir)
+
+		buffer := new(bytes.Buffer)
+		Expect(NewTar().CompressTo(srcDir+"/", buffer)).To(Succeed())
+		Expect(extractor.NewTar().ExtractReader(buffer, destDir)).To(Succeed())
+
+		value := make([]byte, 64)
+		size, err := unix.Lgetxattr(filepath.Join(destDir, "some-file"), "user.archiver", value)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(value[:size])).To(Equal("some-value"))
+	})
+})
@@ -0,0 +1,30 @@
+package compressor
+
+import (
+	"context"
+	"io"
+)
+
+func NewZip(opts ...Option) ArchiveCompressor {
+	return &zipCompressor{opts: opts}
+}
+
+type zipCompressor struct {
+	opts []Option
+}
+
+func (compressor *zipCompressor) Compress(src string, dest string) error {
+	return compressor.CompressContext(context.Background(), src, dest)
+}
+
+func (compressor *zipCompressor) CompressContext(ctx context.Context, src string, dest string) error {
+	return compressToFile(ctx, compressor, src, dest, compressor.opts)
+}
+
+func (compressor *zipCompressor) CompressTo(src string, w io.Writer) error {
+	return compressor.CompressToContext(context.Background(), src, w)
+}
+
+func (compressor *zipCompressor) CompressToContext(ctx context.Context, src string, w io.Writer) error {
+	return WriteZipContext(ctx, src, w, compressor.opts...)
+}
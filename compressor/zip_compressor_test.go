@@ -0,0 +1,110 @@
+package compressor_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "code.cloudfoundry.org/archiver/compressor"
+	"code.cloudfoundry.org/archiver/extractor"
+)
+
+var _ = Describe("Zip Compressor", func() {
+	var compressor ArchiveCompressor
+	var destDir string
+	var extracticator extractor.ArchiveExtractor
+	var victimDir string
+
+	BeforeEach(func() {
+		var err error
+
+		compressor = NewZip()
+		extracticator = extractor.NewZip()
+
+		destDir, err = os.MkdirTemp("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		victimDir, err = os.MkdirTemp("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		err = os.Mkdir(filepath.Join(victimDir, "empty"), 0755)
+		Expect(err).NotTo(HaveOccurred())
+
+		notEmptyDirPath := filepath.Join(victimDir, "not_empty")
+
+		err = os.Mkdir(notEmptyDirPath, 0755)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = os.WriteFile(filepath.Join(notEmptyDirPath, "some_file"), []byte("stuff"), 0644)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = os.Symlink("some_file", filepath.Join(notEmptyDirPath, "some_symlink"))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(destDir)
+		os.RemoveAll(victimDir)
+	})
+
+	It("compresses the src path recursively to dest file", func() {
+		destFile := filepath.Join(destDir, "compress-dst.zip")
+
+		err := compressor.Compress(victimDir+"/", destFile)
+		Expect(err).NotTo(HaveOccurred())
+
+		finalReadingDir, err := os.MkdirTemp(destDir, "final")
+		Expect(err).NotTo(HaveOccurred())
+
+		err = extracticator.Extract(destFile, finalReadingDir)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(retrieveFilePaths(finalReadingDir)).To(Equal(retrieveFilePaths(victimDir)))
+
+		emptyDirInfo, err := os.Stat(filepath.Join(finalReadingDir, "empty"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(emptyDirInfo.IsDir()).To(BeTrue())
+
+		contents, err := os.ReadFile(filepath.Join(finalReadingDir, "not_empty", "some_file"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(contents)).To(Equal("stuff"))
+
+		target, err := os.Readlink(filepath.Join(finalReadingDir, "not_empty", "some_symlink"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(target).To(Equal("some_file"))
+	})
+
+	It("compresses the src path to a writer", func() {
+		buffer := new(bytes.Buffer)
+
+		err := compressor.CompressTo(victimDir+"/", buffer)
+		Expect(err).NotTo(HaveOccurred())
+
+		finalReadingDir, err := os.MkdirTemp(destDir, "final")
+		Expect(err).NotTo(HaveOccurred())
+
+		err = extracticator.ExtractReader(bytes.NewReader(buffer.Bytes()), finalReadingDir)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(retrieveFilePaths(finalReadingDir)).To(Equal(retrieveFilePaths(victimDir)))
+	})
+
+	It("writes identical archives in reproducible mode", func() {
+		compressor = NewZip(WithReproducible())
+
+		first := new(bytes.Buffer)
+		Expect(compressor.CompressTo(victimDir, first)).To(Succeed())
+
+		later := time.Now().Add(time.Hour)
+		Expect(os.Chtimes(filepath.Join(victimDir, "not_empty", "some_file"), later, later)).To(Succeed())
+
+		second := new(bytes.Buffer)
+		Expect(compressor.CompressTo(victimDir, second)).To(Succeed())
+
+		Expect(second.Bytes()).To(Equal(first.Bytes()))
+	})
+})
@@ -0,0 +1,21 @@
+//go:build unix
+
+package extractor
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// atParent calls f with a descriptor for the directory containing path,
+// opened through root, and the last element of path, for the system calls
+// that os.Root has no equivalent of.
+func atParent(root *os.Root, path string, f func(dirfd int, name string) error) error {
+	dir, err := root.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	return f(int(dir.Fd()), filepath.Base(path))
+}
@@ -0,0 +1,118 @@
+package extractor
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// stage makes an empty directory beside dest to extract into, on the same
+// filesystem so that it can be renamed into place. It takes the mode of any
+// existing dest.
+func stage(dest string) (string, error) {
+	err := os.MkdirAll(filepath.Dir(dest), 0755)
+	if err != nil {
+		return "", err
+	}
+
+	staging, err := os.MkdirTemp(filepath.Dir(dest), "."+filepath.Base(dest)+"-staging-")
+	if err != nil {
+		return "", err
+	}
+
+	mode := os.FileMode(0755)
+	if info, err := os.Stat(dest); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	err = os.Chmod(staging, mode)
+	if err != nil {
+		os.RemoveAll(staging)
+		return "", err
+	}
+
+	return staging, nil
+}
+
+// commit moves the staging directory into place, swapping it with anything
+// already at dest, which is then removed. Once dest holds the new tree the
+// extraction has succeeded, so failing to remove the old one is not an
+// error.
+func (x *extraction) commit() error {
+	x.root.Close()
+
+	_, err := os.Lstat(x.dest)
+	switch {
+	case os.IsNotExist(err):
+		err = os.Rename(x.staging, x.dest)
+	case err == nil:
+		err = exchange(x.staging, x.dest)
+		if errors.Is(err, errors.ErrUnsupported) {
+			err = replace(x.staging, x.dest)
+		}
+	}
+
+	if err != nil {
+		removeTree(x.staging)
+		return err
+	}
+
+	removeTree(x.staging)
+	if !x.opts.fsync {
+		return nil
+	}
+
+	// make the rename itself durable
+	start := time.Now()
+	defer func() { x.result.SyncDuration += time.Since(start) }()
+
+	d, err := os.Open(filepath.Dir(x.dest))
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return syncDir(d)
+}
+
+// replace stands in for exchange where the platform cannot swap two paths
+// atomically, leaving dest missing for a moment.
+func replace(staging, dest string) error {
+	old := staging + "-old"
+	err := os.Rename(dest, old)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(staging, dest)
+	if err != nil {
+		os.Rename(old, dest)
+		return err
+	}
+
+	removeTree(old)
+	return nil
+}
+
+// removeTree removes path and everything under it, first making its
+// directories writable, since extraction may have left them read-only.
+func removeTree(path string) error {
+	err := os.RemoveAll(path)
+	if err == nil {
+		return nil
+	}
+
+	filepath.WalkDir(path, func(name string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			info, err := d.Info()
+			if err == nil {
+				os.Chmod(name, info.Mode().Perm()|0700)
+			}
+		}
+		return nil
+	})
+
+	return os.RemoveAll(path)
+}
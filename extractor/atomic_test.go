@@ -0,0 +1,118 @@
+package extractor_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "code.cloudfoundry.org/archiver/extractor"
+	"code.cloudfoundry.org/archiver/extractor/test_helper"
+)
+
+var _ = Describe("Atomic extraction", func() {
+	var parentDir string
+	var extractionDest string
+	var extractionSrc string
+
+	BeforeEach(func() {
+		parentDir = tempDest()
+		extractionDest = filepath.Join(parentDir, "dest")
+		extractionSrc = tempArchive()
+
+		test_helper.CreateTarGZArchive(extractionSrc, []test_helper.ArchiveFile{
+			{Name: "some-dir/", Dir: true},
+			{Name: "some-dir/some-file", Body: "new-contents"},
+		})
+	})
+
+	entriesOf := func(dir string) []string {
+		entries, err := os.ReadDir(dir)
+		Expect(err).NotTo(HaveOccurred())
+
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		return names
+	}
+
+	It("moves the extracted files into place", func() {
+		err := NewTgz(WithAtomic()).Extract(extractionSrc, extractionDest)
+		Expect(err).NotTo(HaveOccurred())
+
+		contents, err := os.ReadFile(filepath.Join(extractionDest, "some-dir", "some-file"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(contents)).To(Equal("new-contents"))
+
+		Expect(entriesOf(parentDir)).To(Equal([]string{"dest"}))
+	})
+
+	Context("when the destination already exists", func() {
+		BeforeEach(func() {
+			Expect(os.MkdirAll(extractionDest, 0750)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(extractionDest, "old-file"), []byte("old-contents"), 0644)).To(Succeed())
+		})
+
+		It("replaces it", func() {
+			err := NewTgz(WithAtomic()).Extract(extractionSrc, extractionDest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(entriesOf(extractionDest)).To(Equal([]string{"some-dir"}))
+			Expect(entriesOf(parentDir)).To(Equal([]string{"dest"}))
+
+			info, err := os.Stat(extractionDest)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0750)))
+		})
+
+		It("replaces it when it holds read-only directories", func() {
+			test_helper.CreateTarGZArchive(extractionSrc, []test_helper.ArchiveFile{
+				{Name: "read-only-dir/", Dir: true, Mode: 0555},
+				{Name: "read-only-dir/some-file", Body: "new-contents"},
+			})
+
+			err := NewTgz(WithAtomic()).Extract(extractionSrc, extractionDest)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = NewTgz(WithAtomic()).Extract(extractionSrc, extractionDest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(entriesOf(extractionDest)).To(Equal([]string{"read-only-dir"}))
+			Expect(entriesOf(parentDir)).To(Equal([]string{"dest"}))
+		})
+
+		It("leaves it untouched when the archive is corrupt", func() {
+			archive, err := os.ReadFile(extractionSrc)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(extractionSrc, archive[:len(archive)/2], 0644)).To(Succeed())
+
+			err = NewTgz(WithAtomic()).Extract(extractionSrc, extractionDest)
+			Expect(err).To(HaveOccurred())
+
+			Expect(entriesOf(extractionDest)).To(Equal([]string{"old-file"}))
+			Expect(entriesOf(parentDir)).To(Equal([]string{"dest"}))
+		})
+
+		It("leaves it untouched when the context is cancelled", func() {
+			test_helper.CreateTarGZArchive(extractionSrc, []test_helper.ArchiveFile{
+				{Name: "first-file", Body: randomText(2048)},
+				{Name: "second-file", Body: randomText(2048)},
+			})
+			archive, err := os.ReadFile(extractionSrc)
+			Expect(err).NotTo(HaveOccurred())
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			err = NewTgz(WithAtomic()).ExtractReaderContext(ctx, &cancellingReader{r: bytes.NewReader(archive), cancel: cancel}, extractionDest)
+			Expect(err).To(MatchError(context.Canceled))
+
+			Expect(entriesOf(extractionDest)).To(Equal([]string{"old-file"}))
+			Expect(entriesOf(parentDir)).To(Equal([]string{"dest"}))
+		})
+	})
+})
@@ -0,0 +1,68 @@
+package extractor
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// ConflictPolicy decides what happens to an archive entry whose path already
+// exists. A directory entry never conflicts with an existing directory; the
+// two are merged, and under every policy the existing directory keeps its
+// mode, times, owner and xattrs.
+type ConflictPolicy int
+
+const (
+	// ConflictOverwrite removes what exists, even a symlink or an empty
+	// directory, and extracts the entry in its place.
+	ConflictOverwrite ConflictPolicy = iota
+	// ConflictSkip keeps what exists and leaves the entry out.
+	ConflictSkip
+	// ConflictKeepNewer keeps what exists when it was modified after the
+	// entry, and overwrites it otherwise.
+	ConflictKeepNewer
+	// ConflictError fails extraction with a *PathExistsError.
+	ConflictError
+)
+
+// PathExistsError reports an archive entry whose path already exists, which
+// extraction rejects under ConflictError.
+type PathExistsError struct {
+	Name string
+}
+
+func (e *PathExistsError) Error() string {
+	return fmt.Sprintf("archive entry %q already exists in the destination", e.Name)
+}
+
+// resolveConflict applies the conflict policy to whatever exists at path
+// before the entry archived as name is extracted there, and reports whether
+// to go on extracting it.
+func (x *extraction) resolveConflict(name, path string, isDir bool, mtime time.Time) (bool, error) {
+	info, err := x.root.Lstat(path)
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if isDir && info.IsDir() {
+		return true, nil
+	}
+
+	switch x.opts.conflicts {
+	case ConflictSkip:
+		x.skip(SkippedConflict)
+		return false, nil
+	case ConflictKeepNewer:
+		if info.ModTime().After(mtime) {
+			x.skip(SkippedConflict)
+			return false, nil
+		}
+	case ConflictError:
+		return false, &PathExistsError{Name: name}
+	}
+
+	return true, x.root.Remove(path)
+}
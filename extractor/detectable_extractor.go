@@ -1,35 +1,108 @@
 package extractor
 
 import (
-	"fmt"
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
 )
 
-type detectableExtractor struct{}
+type detectableExtractor struct {
+	opts []Option
+}
 
-func NewDetectable() Extractor {
-	return &detectableExtractor{}
+func NewDetectable(opts ...Option) ArchiveExtractor {
+	return &detectableExtractor{opts: opts}
 }
 
 func (e *detectableExtractor) Extract(src, dest string) error {
-	srcType, err := mimeType(src)
+	return e.ExtractContext(context.Background(), src, dest)
+}
+
+func (e *detectableExtractor) ExtractContext(ctx context.Context, src, dest string) error {
+	_, err := e.ExtractWithResult(ctx, src, dest)
+	return err
+}
+
+func (e *detectableExtractor) ExtractWithResult(ctx context.Context, src, dest string) (ExtractResult, error) {
+	header, err := sniffFile(src)
+	if err != nil {
+		return ExtractResult{}, err
+	}
+
+	extractor, err := lookupFormat(src, header, e.opts)
+	if err != nil {
+		return ExtractResult{}, err
+	}
+
+	return extractWith(ctx, extractor, src, dest)
+}
+
+func (e *detectableExtractor) ExtractReader(r io.Reader, dest string) error {
+	return e.ExtractReaderContext(context.Background(), r, dest)
+}
+
+func (e *detectableExtractor) ExtractReaderContext(ctx context.Context, r io.Reader, dest string) error {
+	_, err := e.ExtractReaderWithResult(ctx, r, dest)
+	return err
+}
+
+func (e *detectableExtractor) ExtractReaderWithResult(ctx context.Context, r io.Reader, dest string) (ExtractResult, error) {
+	br := bufio.NewReader(r)
+
+	header, err := sniff(br)
 	if err != nil {
-		return err
+		return ExtractResult{}, err
 	}
 
-	switch srcType {
-	case "application/zip":
-		err := NewZip().Extract(src, dest)
-		if err != nil {
-			return err
-		}
-	case "application/x-gzip":
-		err := NewTgz().Extract(src, dest)
-		if err != nil {
-			return err
-		}
-	default:
-		return fmt.Errorf("%s is an unsupported archive type: %s", src, srcType)
+	extractor, err := lookupFormat("", header, e.opts)
+	if err != nil {
+		return ExtractResult{}, err
+	}
+
+	return extractReaderWith(ctx, extractor, br, dest)
+}
+
+// extractWith extracts src with a registered extractor, through the richest
+// of the interfaces it implements.
+func extractWith(ctx context.Context, e Extractor, src, dest string) (ExtractResult, error) {
+	switch e := e.(type) {
+	case ResultExtractor:
+		return e.ExtractWithResult(ctx, src, dest)
+	case ContextExtractor:
+		return ExtractResult{}, e.ExtractContext(ctx, src, dest)
+	}
+
+	err := ctx.Err()
+	if err != nil {
+		return ExtractResult{}, err
+	}
+
+	return ExtractResult{}, e.Extract(src, dest)
+}
+
+// extractReaderWith extracts r with a registered extractor, copying it to a
+// temporary file first if the extractor can only read files.
+func extractReaderWith(ctx context.Context, e Extractor, r io.Reader, dest string) (ExtractResult, error) {
+	switch e := e.(type) {
+	case ResultExtractor:
+		return e.ExtractReaderWithResult(ctx, r, dest)
+	case ReaderExtractor:
+		return ExtractResult{}, e.ExtractReaderContext(ctx, r, dest)
+	}
+
+	tmp, err := os.CreateTemp("", "archiver-stream")
+	if err != nil {
+		return ExtractResult{}, err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, &contextReader{ctx: ctx, r: r})
+	err = errors.Join(err, tmp.Close())
+	if err != nil {
+		return ExtractResult{}, err
 	}
 
-	return nil
+	return extractWith(ctx, e, tmp.Name(), dest)
 }
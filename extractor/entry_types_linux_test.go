@@ -0,0 +1,133 @@
+//go:build linux
+
+package extractor_test
+
+import (
+	"archive/tar"
+	"errors"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/sys/unix"
+
+	. "code.cloudfoundry.org/archiver/extractor"
+)
+
+var _ = Describe("Tar entry types", func() {
+	var extractionDest string
+	var extractionSrc string
+
+	BeforeEach(func() {
+		extractionDest = tempDest()
+		extractionSrc = tempArchive()
+	})
+
+	writeTar := func(headers ...*tar.Header) {
+		file, err := os.Create(extractionSrc)
+		Expect(err).NotTo(HaveOccurred())
+		defer file.Close()
+
+		w := tar.NewWriter(file)
+		for _, header := range headers {
+			Expect(w.WriteHeader(header)).To(Succeed())
+		}
+		Expect(w.Close()).To(Succeed())
+	}
+
+	someFile := &tar.Header{Name: "some-file", Typeflag: tar.TypeReg, Mode: 0644}
+	nullDevice := &tar.Header{Name: "null", Typeflag: tar.TypeChar, Mode: 0666, Devmajor: 1, Devminor: 3}
+
+	It("skips pax global headers", func() {
+		writeTar(
+			&tar.Header{
+				Name:       "pax_global_header",
+				Typeflag:   tar.TypeXGlobalHeader,
+				PAXRecords: map[string]string{"comment": "1234abcd"},
+			},
+			someFile,
+		)
+
+		err := NewTar(WithUnsupportedEntries(UnsupportedError)).Extract(extractionSrc, extractionDest)
+		Expect(err).NotTo(HaveOccurred())
+
+		entries, err := os.ReadDir(extractionDest)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0].Name()).To(Equal("some-file"))
+	})
+
+	It("creates FIFOs", func() {
+		writeTar(&tar.Header{Name: "some-fifo", Typeflag: tar.TypeFifo, Mode: 0640})
+
+		err := NewTar().Extract(extractionSrc, extractionDest)
+		Expect(err).NotTo(HaveOccurred())
+
+		info, err := os.Lstat(filepath.Join(extractionDest, "some-fifo"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode() & os.ModeNamedPipe).NotTo(BeZero())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0640)))
+	})
+
+	Context("with devices", func() {
+		It("skips them by default", func() {
+			writeTar(nullDevice, someFile)
+
+			err := NewTar().Extract(extractionSrc, extractionDest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(extractionDest, "null")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(extractionDest, "some-file")).To(BeAnExistingFile())
+		})
+
+		It("fails on them with UnsupportedError", func() {
+			writeTar(nullDevice, someFile)
+
+			err := NewTar(WithUnsupportedEntries(UnsupportedError)).Extract(extractionSrc, extractionDest)
+
+			var unsupportedErr *UnsupportedEntryError
+			Expect(errors.As(err, &unsupportedErr)).To(BeTrue(), "expected an *UnsupportedEntryError, got %v", err)
+			Expect(unsupportedErr.Name).To(Equal("null"))
+			Expect(unsupportedErr.Typeflag).To(Equal(byte(tar.TypeChar)))
+		})
+
+		It("creates them WithDevices when privileged", func() {
+			writeTar(nullDevice)
+
+			err := NewTar(WithDevices(), WithExactPermissions(), WithUnsupportedEntries(UnsupportedError)).Extract(extractionSrc, extractionDest)
+			if errors.As(err, new(*UnsupportedEntryError)) {
+				Skip("the process may not create devices")
+			}
+			Expect(err).NotTo(HaveOccurred())
+
+			var stat unix.Stat_t
+			Expect(unix.Lstat(filepath.Join(extractionDest, "null"), &stat)).To(Succeed())
+			Expect(stat.Mode & unix.S_IFMT).To(Equal(uint32(unix.S_IFCHR)))
+			Expect(unix.Major(stat.Rdev)).To(Equal(uint32(1)))
+			Expect(unix.Minor(stat.Rdev)).To(Equal(uint32(3)))
+			Expect(stat.Mode & 0777).To(Equal(uint32(0666)))
+		})
+	})
+
+	Context("with types this package does not know", func() {
+		volumeHeader := &tar.Header{Name: "some-volume", Typeflag: 'V', Format: tar.FormatGNU}
+
+		It("skips them by default", func() {
+			writeTar(volumeHeader, someFile)
+
+			err := NewTar().Extract(extractionSrc, extractionDest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(extractionDest, "some-volume")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(extractionDest, "some-file")).To(BeAnExistingFile())
+		})
+
+		It("fails on them with UnsupportedError", func() {
+			writeTar(volumeHeader)
+
+			err := NewTar(WithUnsupportedEntries(UnsupportedError)).Extract(extractionSrc, extractionDest)
+			Expect(err).To(MatchError(`archive entry "some-volume" has unsupported type 'V'`))
+		})
+	})
+})
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package extractor
+
+import "errors"
+
+func exchange(_, _ string) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,11 @@
+package extractor
+
+// SetProcSelfFd replaces the directory extended attributes are set through,
+// so that tests can extract as if /proc were not mounted.
+func SetProcSelfFd(dir string) (restore func()) {
+	original := procSelfFd
+	procSelfFd = dir
+	return func() {
+		procSelfFd = original
+	}
+}
@@ -0,0 +1,15 @@
+package extractor
+
+import "os"
+
+// SetLchown replaces the function that restores ownership, so that tests can
+// record it without running as root. Names are relative to the destination.
+func SetLchown(f func(name string, uid, gid int) error) (restore func()) {
+	original := lchown
+	lchown = func(_ *os.Root, name string, uid, gid int) error {
+		return f(name, uid, gid)
+	}
+	return func() {
+		lchown = original
+	}
+}
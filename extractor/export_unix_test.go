@@ -0,0 +1,23 @@
+//go:build unix
+
+package extractor
+
+// RereadUmask rereads the umask that stands in when /proc cannot report it,
+// as if the process had started with the current one.
+func RereadUmask() (restore func()) {
+	original := initialUmask
+	initialUmask = swapUmask()
+	return func() {
+		initialUmask = original
+	}
+}
+
+// SetProcSelfStatus replaces the file the umask is read from, so that tests
+// can extract as if /proc were not mounted.
+func SetProcSelfStatus(path string) (restore func()) {
+	original := procSelfStatus
+	procSelfStatus = path
+	return func() {
+		procSelfStatus = original
+	}
+}
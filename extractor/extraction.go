@@ -0,0 +1,408 @@
+package extractor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var lchown = func(root *os.Root, name string, uid, gid int) error {
+	return root.Lchown(name, uid, gid)
+}
+
+// extraction writes entries through an os.Root, so that no symlink, whether
+// extracted earlier or swapped in by another process, can redirect a write
+// outside of the destination. Paths are relative to the root throughout.
+type extraction struct {
+	ctx     context.Context
+	dest    string
+	root    *os.Root
+	opts    *options
+	created []string
+
+	// staging is where an atomic extraction writes until it is committed
+	staging string
+
+	// dirs are finished once everything inside them has been written
+	dirs []deferredDir
+
+	// made are the directories this extraction created, the only ones it
+	// gives archived metadata
+	made map[string]bool
+
+	// entry is the name of the entry being extracted, and the rest count
+	// what has been extracted so far, for ExtractLimits
+	entry        string
+	entries      int
+	written      int64
+	archiveBytes func() int64
+
+	// links are the symlinks to check again once everything is extracted
+	links []extractedLink
+
+	// touched are the directories to sync under WithFsync
+	touched map[string]bool
+
+	result ExtractResult
+}
+
+type deferredDir struct {
+	path  string
+	mode  os.FileMode
+	atime time.Time
+	mtime time.Time
+}
+
+func newExtraction(ctx context.Context, dest string, o *options) (*extraction, error) {
+	x := &extraction{ctx: ctx, dest: dest, opts: o, made: map[string]bool{}}
+	o.processUmask = processUmask()
+
+	dir := dest
+	if o.atomic {
+		var err error
+		x.dest, err = filepath.Abs(dest)
+		if err != nil {
+			return nil, err
+		}
+
+		x.staging, err = stage(x.dest)
+		if err != nil {
+			return nil, err
+		}
+		dir = x.staging
+	} else {
+		err := os.MkdirAll(dest, 0755)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		if x.staging != "" {
+			removeTree(x.staging)
+		}
+		return nil, err
+	}
+
+	x.root = root
+	return x, nil
+}
+
+func (x *extraction) close() error {
+	return x.root.Close()
+}
+
+// PathTraversalError reports an archive entry whose name reaches outside of
+// the destination, which extraction rejects in strict path mode.
+type PathTraversalError struct {
+	Name string
+}
+
+func (e *PathTraversalError) Error() string {
+	return fmt.Sprintf("archive entry %q points outside of the destination", e.Name)
+}
+
+// entryPath returns the path of an archive entry within the root. Leading
+// ".." components are dropped rather than allowed to climb out, unless the
+// extraction is strict about paths.
+func (x *extraction) entryPath(name string) (string, error) {
+	if x.opts.strictPaths && escapes(name) {
+		return "", &PathTraversalError{Name: name}
+	}
+
+	slashed := filepath.ToSlash(name)
+	cleaned := strings.TrimPrefix(path.Clean("/"+slashed), "/")
+	if cleaned == "" {
+		cleaned = "."
+	}
+
+	if cleaned != path.Clean(slashed) {
+		x.result.PathRewrites = append(x.result.PathRewrites, Rewrite{Name: name, From: name, To: filepath.FromSlash(cleaned)})
+	}
+
+	return filepath.FromSlash(cleaned), nil
+}
+
+// escapes reports whether name is absolute, starts with a Windows drive or
+// UNC share, or has a ".." component, whichever separators it uses.
+func escapes(name string) bool {
+	if strings.HasPrefix(name, "/") || strings.HasPrefix(name, `\`) {
+		return true
+	}
+
+	if len(name) >= 2 && name[1] == ':' && unicode.IsLetter(rune(name[0])) {
+		return true
+	}
+
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if part == ".." {
+			return true
+		}
+	}
+
+	return false
+}
+
+// track records the topmost ancestor of path that does not exist yet, so
+// that everything this extraction creates can be removed if it is cancelled.
+func (x *extraction) track(path string) {
+	top := ""
+	for p := filepath.Clean(path); p != "." && p != filepath.Dir(p); p = filepath.Dir(p) {
+		if _, err := x.root.Lstat(p); err == nil {
+			break
+		}
+		top = p
+	}
+
+	if top != "" {
+		x.created = append(x.created, top)
+	}
+}
+
+// touch records that path and the directories above it have changed, for
+// finish to sync under WithFsync.
+func (x *extraction) touch(path string) {
+	if !x.opts.fsync {
+		return
+	}
+
+	if x.touched == nil {
+		x.touched = map[string]bool{}
+	}
+
+	for p := filepath.Dir(filepath.Clean(path)); !x.touched[p]; p = filepath.Dir(p) {
+		x.touched[p] = true
+		if p == "." {
+			break
+		}
+	}
+}
+
+// mkdir creates a directory the extraction can keep writing into, whatever
+// its archived mode; finish applies that mode once its contents are written,
+// the way GNU tar delays setting directory metadata. A directory that existed
+// before the extraction, such as the destination itself, keeps its metadata,
+// and callers check made before giving it an owner or xattrs.
+func (x *extraction) mkdir(path string, mode os.FileMode, atime, mtime time.Time) error {
+	err := x.mkdirAll(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+
+	err = x.root.Mkdir(path, 0700)
+	if err != nil && !os.IsExist(err) {
+		return err
+	}
+
+	if err == nil {
+		x.made[path] = true
+		x.extracted(&x.result.Dirs, path, 0)
+	}
+
+	if !x.made[path] {
+		return nil
+	}
+
+	if atime.IsZero() {
+		atime = mtime
+	}
+
+	x.dirs = append(x.dirs, deferredDir{path: path, mode: mode, atime: atime, mtime: mtime})
+	return nil
+}
+
+// mkdirAll creates dir and any missing parents, which have no entries of
+// their own, recording the ones it creates.
+func (x *extraction) mkdirAll(dir string) error {
+	var missing []string
+	for p := filepath.Clean(dir); p != "." && p != filepath.Dir(p); p = filepath.Dir(p) {
+		if _, err := x.root.Lstat(p); err == nil {
+			break
+		}
+		missing = append(missing, p)
+	}
+
+	err := x.root.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	for i := len(missing) - 1; i >= 0; i-- {
+		x.made[missing[i]] = true
+		x.result.Dirs = append(x.result.Dirs, ExtractedEntry{Path: missing[i]})
+	}
+	return nil
+}
+
+func (x *extraction) setTimes(path string, mode os.FileMode, atime, mtime time.Time) error {
+	if mtime.IsZero() {
+		return nil
+	}
+
+	if atime.IsZero() {
+		atime = mtime
+	}
+
+	switch {
+	case mode&os.ModeSymlink != 0:
+		return lchtimes(x.root, path, atime, mtime)
+	default:
+		return x.root.Chtimes(path, atime, mtime)
+	}
+}
+
+// finish checks the symlinks again and applies the deferred directory
+// metadata, deepest first, so that a read-only directory is not closed off
+// before its subdirectories are done.
+func (x *extraction) finish() (ExtractResult, error) {
+	err := x.checkLinks()
+	if err != nil {
+		return ExtractResult{}, x.abort(err)
+	}
+
+	slices.SortStableFunc(x.dirs, func(a, b deferredDir) int {
+		return strings.Count(b.path, string(filepath.Separator)) - strings.Count(a.path, string(filepath.Separator))
+	})
+
+	for _, dir := range x.dirs {
+		err := x.root.Chmod(dir.path, x.opts.permissions(dir.mode))
+		if err != nil {
+			return ExtractResult{}, x.abort(err)
+		}
+
+		if dir.mtime.IsZero() {
+			continue
+		}
+
+		err = x.root.Chtimes(dir.path, dir.atime, dir.mtime)
+		if err != nil {
+			return ExtractResult{}, x.abort(err)
+		}
+	}
+
+	err = x.syncDirs()
+	if err != nil {
+		return ExtractResult{}, x.abort(err)
+	}
+
+	if x.staging != "" {
+		err = x.commit()
+		if err != nil {
+			return ExtractResult{}, err
+		}
+	}
+
+	x.result.BytesWritten = x.written
+	return x.result, nil
+}
+
+// syncDirs syncs the touched directories once everything has been written,
+// rather than after every entry.
+func (x *extraction) syncDirs() error {
+	if len(x.touched) == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	defer func() { x.result.SyncDuration += time.Since(start) }()
+
+	for _, dir := range slices.Sorted(maps.Keys(x.touched)) {
+		d, err := x.root.Open(dir)
+		if err != nil {
+			return err
+		}
+
+		err = syncDir(d)
+		d.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// chown gives path its archived owner when ownership is restored. It must
+// come before any chmod, since changing the owner clears setuid and setgid.
+func (x *extraction) chown(path string, uid, gid int) error {
+	if !x.opts.ownership {
+		return nil
+	}
+
+	uid, gid = x.opts.hostOwner(uid, gid)
+	if uid < 0 && gid < 0 {
+		return nil
+	}
+
+	return lchown(x.root, path, uid, gid)
+}
+
+// abort cleans up after a failed extraction: an atomic one is discarded
+// whole, and a cancelled one loses whatever it had created.
+func (x *extraction) abort(err error) error {
+	ctxErr := x.ctx.Err()
+
+	switch {
+	case x.staging != "":
+		x.root.Close()
+		removeTree(x.staging)
+	case ctxErr != nil:
+		for i := len(x.created) - 1; i >= 0; i-- {
+			x.root.RemoveAll(x.created[i])
+		}
+	}
+
+	if ctxErr != nil {
+		return ctxErr
+	}
+
+	return err
+}
+
+// writeFile copies input into filePath and then chmods it, since creating
+// the file with its archived mode would leave it subject to the umask and
+// drop any setuid, setgid or sticky bits. The file is returned open, for the
+// caller to sync once the rest of its metadata is applied, and to close.
+func (x *extraction) writeFile(filePath string, mode os.FileMode, uid, gid int, input io.Reader) (*os.File, error) {
+	fileCopy, err := x.root.OpenFile(filePath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := io.Copy(&limitWriter{x: x, w: fileCopy}, &contextReader{ctx: x.ctx, r: input})
+	if err == nil {
+		x.extracted(&x.result.Files, filePath, size)
+		err = x.chown(filePath, uid, gid)
+	}
+	if err == nil {
+		err = fileCopy.Chmod(x.opts.permissions(mode))
+	}
+	if err != nil {
+		fileCopy.Close()
+		return nil, err
+	}
+
+	return fileCopy, nil
+}
+
+// sync flushes a file written by writeFile under WithFsync, after its xattrs
+// and times, so that they are part of what is synced.
+func (x *extraction) sync(file *os.File) error {
+	if file == nil || !x.opts.fsync {
+		return nil
+	}
+
+	start := time.Now()
+	err := file.Sync()
+	x.result.SyncDuration += time.Since(start)
+	return err
+}
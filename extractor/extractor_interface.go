@@ -1,5 +1,36 @@
 package extractor
 
+import (
+	"context"
+	"io"
+)
+
 type Extractor interface {
 	Extract(src, dest string) error
 }
+
+// ContextExtractor is an Extractor that stops when ctx is cancelled.
+type ContextExtractor interface {
+	Extractor
+	ExtractContext(ctx context.Context, src, dest string) error
+}
+
+// ReaderExtractor extracts an archive read from r rather than a file.
+type ReaderExtractor interface {
+	ExtractReader(r io.Reader, dest string) error
+	ExtractReaderContext(ctx context.Context, r io.Reader, dest string) error
+}
+
+// ResultExtractor reports what an extraction wrote, skipped and rewrote.
+type ResultExtractor interface {
+	ExtractWithResult(ctx context.Context, src, dest string) (ExtractResult, error)
+	ExtractReaderWithResult(ctx context.Context, r io.Reader, dest string) (ExtractResult, error)
+}
+
+// ArchiveExtractor is every way of extracting an archive, which the
+// extractors in this package implement.
+type ArchiveExtractor interface {
+	ContextExtractor
+	ReaderExtractor
+	ResultExtractor
+}
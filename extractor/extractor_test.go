@@ -1,41 +1,42 @@
 package extractor_test
 
 import (
+	"archive/tar"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"math"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
 	. "code.cloudfoundry.org/archiver/extractor"
+	"code.cloudfoundry.org/archiver/extractor/fake_extractor"
 	"code.cloudfoundry.org/archiver/extractor/test_helper"
 )
 
 var _ = Describe("Extractor", func() {
-	var extractor Extractor
+	var extractor ArchiveExtractor
+
+	var newExtractor func(...Option) ArchiveExtractor
+	var createArchive func(string, []test_helper.ArchiveFile)
 
 	var extractionDest string
 	var extractionSrc string
 
 	BeforeEach(func() {
-		var err error
-
-		archive, err := os.CreateTemp("", "extractor-archive")
-		Expect(err).NotTo(HaveOccurred())
-
-		extractionDest, err = os.MkdirTemp("", "extracted")
-		Expect(err).NotTo(HaveOccurred())
-
-		extractionSrc = archive.Name()
+		extractionSrc = tempArchive()
+		extractionDest = tempDest()
 
 		extractor = NewDetectable()
 	})
 
-	AfterEach(func() {
-		os.RemoveAll(extractionSrc)
-		os.RemoveAll(extractionDest)
-	})
-
 	archiveFiles := []test_helper.ArchiveFile{
 		{
 			Name: "./",
@@ -69,10 +70,97 @@ var _ = Describe("Extractor", func() {
 		},
 	}
 
-	extractionTest := func() {
+	partialArchiveFiles := []test_helper.ArchiveFile{
+		{
+			Name: "some-dir/",
+			Dir:  true,
+		},
+		{
+			Name: "some-dir/some-file",
+			Body: randomText(2048),
+		},
+	}
+
+	dirTime := time.Date(2001, time.February, 3, 4, 5, 6, 0, time.UTC)
+	fileTime := time.Date(2002, time.March, 4, 5, 6, 7, 0, time.UTC)
+	linkTime := time.Date(2003, time.April, 5, 6, 7, 8, 0, time.UTC)
+
+	timedArchiveFiles := []test_helper.ArchiveFile{
+		{
+			Name:    "some-dir/",
+			Dir:     true,
+			ModTime: dirTime,
+		},
+		{
+			Name:    "some-dir/some-file",
+			Body:    "some-file-contents",
+			ModTime: fileTime,
+		},
+		{
+			Name:    "some-dir/some-symlink",
+			Link:    "some-file",
+			ModTime: linkTime,
+		},
+	}
+
+	timesTest := func() {
+		err := extractor.Extract(extractionSrc, extractionDest)
+		Expect(err).NotTo(HaveOccurred())
+
+		dirInfo, err := os.Stat(filepath.Join(extractionDest, "some-dir"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dirInfo.ModTime()).To(BeTemporally("==", dirTime))
+
+		fileInfo, err := os.Stat(filepath.Join(extractionDest, "some-dir", "some-file"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fileInfo.ModTime()).To(BeTemporally("==", fileTime))
+
+		if runtime.GOOS != "windows" {
+			linkInfo, err := os.Lstat(filepath.Join(extractionDest, "some-dir", "some-symlink"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(linkInfo.ModTime()).To(BeTemporally("==", linkTime))
+		}
+	}
+
+	readOnlyArchiveFiles := []test_helper.ArchiveFile{
+		{
+			Name: "read-only-dir/",
+			Dir:  true,
+			Mode: 0555,
+		},
+		{
+			Name: "read-only-dir/nested-dir/",
+			Dir:  true,
+			Mode: 0555,
+		},
+		{
+			Name: "read-only-dir/nested-dir/some-file",
+			Body: "some-file-contents",
+			Mode: 0444,
+		},
+	}
+
+	readOnlyTest := func() {
+		DeferCleanup(func() {
+			os.Chmod(filepath.Join(extractionDest, "read-only-dir"), 0755)
+			os.Chmod(filepath.Join(extractionDest, "read-only-dir", "nested-dir"), 0755)
+		})
+
 		err := extractor.Extract(extractionSrc, extractionDest)
 		Expect(err).NotTo(HaveOccurred())
 
+		fileContents, err := os.ReadFile(filepath.Join(extractionDest, "read-only-dir", "nested-dir", "some-file"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(fileContents)).To(Equal("some-file-contents"))
+
+		for _, dir := range []string{"read-only-dir", filepath.Join("read-only-dir", "nested-dir")} {
+			dirInfo, err := os.Stat(filepath.Join(extractionDest, dir))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dirInfo.Mode().Perm()).To(Equal(os.FileMode(0555)))
+		}
+	}
+
+	expectExtracted := func() {
 		fileContents, err := os.ReadFile(filepath.Join(extractionDest, "some-file"))
 		Expect(err).NotTo(HaveOccurred())
 		Expect(string(fileContents)).To(Equal("some-file-contents"))
@@ -106,13 +194,392 @@ var _ = Describe("Extractor", func() {
 		Expect(symlinkInfo.Mode() & 0755).To(Equal(os.FileMode(0755)))
 	}
 
+	extractionTest := func() {
+		err := extractor.Extract(extractionSrc, extractionDest)
+		Expect(err).NotTo(HaveOccurred())
+
+		expectExtracted()
+	}
+
+	readerExtractionTest := func() {
+		archive, err := os.Open(extractionSrc)
+		Expect(err).NotTo(HaveOccurred())
+		defer archive.Close()
+
+		err = extractor.ExtractReader(streamReader{archive}, extractionDest)
+		Expect(err).NotTo(HaveOccurred())
+
+		expectExtracted()
+	}
+
+	cancellationTest := func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := extractor.ExtractContext(ctx, extractionSrc, extractionDest)
+		Expect(err).To(MatchError(context.Canceled))
+
+		entries, err := os.ReadDir(extractionDest)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+	}
+
+	streamCancellationTest := func() {
+		archive, err := os.Open(extractionSrc)
+		Expect(err).NotTo(HaveOccurred())
+		defer archive.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		err = extractor.ExtractReaderContext(ctx, &cancellingReader{r: archive, cancel: cancel}, extractionDest)
+		Expect(err).To(MatchError(context.Canceled))
+
+		entries, err := os.ReadDir(extractionDest)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+	}
+
+	badNames := []TableEntry{
+		Entry("a parent directory", "../some-file"),
+		Entry("a parent directory further in", "some-dir/../../some-file"),
+		Entry("a parent directory with backslashes", `..\some-file`),
+		Entry("an absolute path", "/some-dir/some-file"),
+		Entry("a Windows drive", "C:/some-file"),
+		Entry("a Windows drive with backslashes", `C:\some-file`),
+		Entry("a UNC share", `\\some-server\some-share\some-file`),
+	}
+
+	strictPathsTest := func(name string) {
+		createArchive(extractionSrc, []test_helper.ArchiveFile{
+			{Name: "some-file", Body: "some-file-contents"},
+			{Name: name, Body: "bad-file-contents"},
+		})
+
+		err := newExtractor(WithStrictPaths()).Extract(extractionSrc, extractionDest)
+
+		var traversalErr *PathTraversalError
+		Expect(errors.As(err, &traversalErr)).To(BeTrue(), "expected a *PathTraversalError, got %v", err)
+		Expect(traversalErr.Name).To(Equal(name))
+	}
+
+	strictPathsInsideTest := func() {
+		createArchive(extractionSrc, []test_helper.ArchiveFile{
+			{Name: "./some-dir/", Dir: true},
+			{Name: "./some-dir/some-file", Body: "some-file-contents"},
+			{Name: "some-dir/..some-file", Body: "other-file-contents"},
+		})
+
+		err := newExtractor(WithStrictPaths()).Extract(extractionSrc, extractionDest)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(filepath.Join(extractionDest, "some-dir", "some-file")).To(BeAnExistingFile())
+		Expect(filepath.Join(extractionDest, "some-dir", "..some-file")).To(BeAnExistingFile())
+	}
+
+	archivedAt := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	conflictingArchiveFiles := []test_helper.ArchiveFile{
+		{Name: "some-dir/", Dir: true, ModTime: archivedAt},
+		{Name: "some-dir/some-file", Body: "new-contents", ModTime: archivedAt},
+		{Name: "some-link", Link: "some-dir/some-file", ModTime: archivedAt},
+	}
+
+	createConflicts := func() {
+		if runtime.GOOS == "windows" {
+			Skip("symlinks need elevated privileges on Windows")
+		}
+
+		createArchive(extractionSrc, conflictingArchiveFiles)
+
+		someDir := filepath.Join(extractionDest, "some-dir")
+		Expect(os.Mkdir(someDir, 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(someDir, "existing-file"), []byte("existing-contents"), 0644)).To(Succeed())
+
+		newer := archivedAt.Add(time.Hour)
+		someFile := filepath.Join(someDir, "some-file")
+		Expect(os.WriteFile(someFile, []byte("old-contents"), 0644)).To(Succeed())
+		Expect(os.Chtimes(someFile, newer, newer)).To(Succeed())
+
+		older := archivedAt.Add(-time.Hour)
+		someLink := filepath.Join(extractionDest, "some-link")
+		Expect(os.WriteFile(someLink, []byte("old-link-contents"), 0644)).To(Succeed())
+		Expect(os.Chtimes(someLink, older, older)).To(Succeed())
+	}
+
+	contentsOf := func(name string) string {
+		contents, err := os.ReadFile(filepath.Join(extractionDest, name))
+		Expect(err).NotTo(HaveOccurred())
+		return string(contents)
+	}
+
+	isSymlink := func(name string) bool {
+		info, err := os.Lstat(filepath.Join(extractionDest, name))
+		Expect(err).NotTo(HaveOccurred())
+		return info.Mode()&os.ModeSymlink != 0
+	}
+
+	conflictOverwriteTest := func() {
+		err := newExtractor().Extract(extractionSrc, extractionDest)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(contentsOf("some-dir/some-file")).To(Equal("new-contents"))
+		Expect(isSymlink("some-link")).To(BeTrue())
+		Expect(contentsOf("some-dir/existing-file")).To(Equal("existing-contents"))
+	}
+
+	conflictSymlinkTest := func() {
+		someFile := filepath.Join(extractionDest, "some-dir", "some-file")
+		Expect(os.Remove(someFile)).To(Succeed())
+		Expect(os.Symlink("../some-dir/existing-file", someFile)).To(Succeed())
+
+		err := newExtractor(WithConflictPolicy(ConflictOverwrite)).Extract(extractionSrc, extractionDest)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(isSymlink("some-dir/some-file")).To(BeFalse())
+		Expect(contentsOf("some-dir/some-file")).To(Equal("new-contents"))
+		Expect(contentsOf("some-dir/existing-file")).To(Equal("existing-contents"))
+	}
+
+	conflictSkipTest := func() {
+		err := newExtractor(WithConflictPolicy(ConflictSkip)).Extract(extractionSrc, extractionDest)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(contentsOf("some-dir/some-file")).To(Equal("old-contents"))
+		Expect(isSymlink("some-link")).To(BeFalse())
+	}
+
+	conflictKeepNewerTest := func() {
+		err := newExtractor(WithConflictPolicy(ConflictKeepNewer)).Extract(extractionSrc, extractionDest)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(contentsOf("some-dir/some-file")).To(Equal("old-contents"))
+		Expect(isSymlink("some-link")).To(BeTrue())
+	}
+
+	conflictErrorTest := func() {
+		err := newExtractor(WithConflictPolicy(ConflictError)).Extract(extractionSrc, extractionDest)
+
+		var existsErr *PathExistsError
+		Expect(errors.As(err, &existsErr)).To(BeTrue(), "expected a *PathExistsError, got %v", err)
+		Expect(existsErr.Name).To(Equal("some-dir/some-file"))
+	}
+
+	mergePolicies := []TableEntry{
+		Entry("ConflictOverwrite", ConflictOverwrite),
+		Entry("ConflictSkip", ConflictSkip),
+		Entry("ConflictKeepNewer", ConflictKeepNewer),
+		Entry("ConflictError", ConflictError),
+	}
+
+	conflictMergeTest := func(policy ConflictPolicy) {
+		createArchive(extractionSrc, []test_helper.ArchiveFile{
+			{Name: "some-dir/", Dir: true, Mode: 0700, ModTime: archivedAt},
+			{Name: "some-dir/other-file", Body: "other-contents"},
+		})
+
+		someDir := filepath.Join(extractionDest, "some-dir")
+		Expect(os.Chmod(someDir, 0755)).To(Succeed())
+
+		err := newExtractor(WithConflictPolicy(policy)).Extract(extractionSrc, extractionDest)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(contentsOf("some-dir/other-file")).To(Equal("other-contents"))
+		Expect(contentsOf("some-dir/existing-file")).To(Equal("existing-contents"))
+
+		info, err := os.Stat(someDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0755)))
+		Expect(info.ModTime()).NotTo(BeTemporally("==", archivedAt))
+	}
+
+	syncedArchiveFiles := []test_helper.ArchiveFile{
+		{Name: "some-dir/", Dir: true},
+		{Name: "some-dir/some-file", Body: "some-file-contents", ModTime: fileTime},
+		{Name: "some-dir/nested-dir/other-file", Body: "other-file-contents"},
+	}
+
+	fsyncTest := func() {
+		result, err := newExtractor(WithFsync()).ExtractWithResult(context.Background(), extractionSrc, extractionDest)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.SyncDuration).To(BeNumerically(">", 0))
+
+		Expect(contentsOf("some-dir/nested-dir/other-file")).To(Equal("other-file-contents"))
+
+		fileInfo, err := os.Stat(filepath.Join(extractionDest, "some-dir", "some-file"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fileInfo.ModTime()).To(BeTemporally("==", fileTime))
+	}
+
+	noFsyncTest := func() {
+		result, err := newExtractor().ExtractWithResult(context.Background(), extractionSrc, extractionDest)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.SyncDuration).To(BeZero())
+	}
+
+	atomicFsyncTest := func() {
+		dest := filepath.Join(extractionDest, "dest")
+
+		result, err := newExtractor(WithFsync(), WithAtomic()).ExtractWithResult(context.Background(), extractionSrc, dest)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.SyncDuration).To(BeNumerically(">", 0))
+		Expect(filepath.Join(dest, "some-dir", "some-file")).To(BeAnExistingFile())
+	}
+
+	createResultArchive := func() {
+		if runtime.GOOS == "windows" {
+			Skip("symlinks need elevated privileges on Windows")
+		}
+
+		createArchive(extractionSrc, []test_helper.ArchiveFile{
+			{Name: "some-dir/", Dir: true},
+			{Name: "some-dir/some-file", Body: "some-file-contents"},
+			{Name: "../escaping-file", Body: "escaping-contents"},
+			{Name: "some-dir/escaping-link", Link: "/etc/passwd"},
+			{Name: "existing-file", Body: "new-contents"},
+		})
+
+		Expect(os.WriteFile(filepath.Join(extractionDest, "existing-file"), []byte("old-contents"), 0644)).To(Succeed())
+	}
+
+	resultTest := func() {
+		result, err := newExtractor(
+			WithSymlinkPolicy(SymlinkRewrite),
+			WithConflictPolicy(ConflictSkip),
+		).ExtractWithResult(context.Background(), extractionSrc, extractionDest)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(result.Dirs).To(Equal([]ExtractedEntry{
+			{Name: "some-dir/", Path: "some-dir"},
+		}))
+		Expect(result.Files).To(Equal([]ExtractedEntry{
+			{Name: "some-dir/some-file", Path: filepath.Join("some-dir", "some-file"), Size: 18},
+			{Name: "../escaping-file", Path: "escaping-file", Size: 17},
+		}))
+		Expect(result.Symlinks).To(Equal([]ExtractedEntry{
+			{Name: "some-dir/escaping-link", Path: filepath.Join("some-dir", "escaping-link"), Size: int64(len("../etc/passwd"))},
+		}))
+		Expect(result.Skipped).To(Equal([]SkippedEntry{
+			{Name: "existing-file", Reason: SkippedConflict},
+		}))
+		Expect(result.PathRewrites).To(Equal([]Rewrite{
+			{Name: "../escaping-file", From: "../escaping-file", To: "escaping-file"},
+		}))
+		Expect(result.SymlinkRewrites).To(Equal([]Rewrite{
+			{Name: "some-dir/escaping-link", From: "/etc/passwd", To: "../etc/passwd"},
+		}))
+		Expect(result.BytesWritten).To(Equal(int64(35)))
+	}
+
+	resultParentDirsTest := func() {
+		createArchive(extractionSrc, []test_helper.ArchiveFile{
+			{Name: "a/b/some-file", Body: "some-file-contents"},
+			{Name: "existing-dir/", Dir: true},
+			{Name: "existing-dir/c/other-file", Body: "other-file-contents"},
+		})
+		Expect(os.Mkdir(filepath.Join(extractionDest, "existing-dir"), 0755)).To(Succeed())
+
+		result, err := newExtractor().ExtractWithResult(context.Background(), extractionSrc, extractionDest)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(result.Dirs).To(Equal([]ExtractedEntry{
+			{Path: "a"},
+			{Path: filepath.Join("a", "b")},
+			{Path: filepath.Join("existing-dir", "c")},
+		}))
+	}
+
+	resultSkippedSymlinkTest := func() {
+		result, err := newExtractor(WithSymlinkPolicy(SymlinkSkip)).ExtractWithResult(context.Background(), extractionSrc, extractionDest)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(result.Symlinks).To(BeEmpty())
+		Expect(result.Skipped).To(Equal([]SkippedEntry{
+			{Name: "some-dir/escaping-link", Reason: SkippedSymlink},
+		}))
+	}
+
 	Context("when the file is a zip archive", func() {
 		BeforeEach(func() {
-			test_helper.CreateZipArchive(extractionSrc, archiveFiles)
+			newExtractor = NewZip
+			createArchive = test_helper.CreateZipArchive
+			createArchive(extractionSrc, archiveFiles)
 		})
 
 		It("extracts the ZIP's files, generating directories, and honoring file permissions and symlinks", extractionTest)
 
+		It("stops extracting when the context is cancelled", cancellationTest)
+
+		It("extracts from a stream", readerExtractionTest)
+
+		Context("when the context is cancelled mid-stream", func() {
+			BeforeEach(func() {
+				test_helper.CreateZipArchive(extractionSrc, partialArchiveFiles)
+			})
+
+			It("removes what it extracted", streamCancellationTest)
+		})
+
+		Context("with strict paths", func() {
+			DescribeTable("rejects entries naming", strictPathsTest, badNames)
+
+			It("extracts entries that stay inside the destination", strictPathsInsideTest)
+		})
+
+		Context("with existing paths", func() {
+			BeforeEach(createConflicts)
+
+			It("overwrites them by default, replacing files with symlinks", conflictOverwriteTest)
+
+			It("replaces symlinks rather than writing through them", conflictSymlinkTest)
+
+			It("keeps them with ConflictSkip", conflictSkipTest)
+
+			It("keeps only those newer than their entries with ConflictKeepNewer", conflictKeepNewerTest)
+
+			It("fails with ConflictError", conflictErrorTest)
+
+			DescribeTable("merges directories into them, keeping their metadata", conflictMergeTest, mergePolicies)
+		})
+
+		Context("with fsync", func() {
+			BeforeEach(func() {
+				test_helper.CreateZipArchive(extractionSrc, syncedArchiveFiles)
+			})
+
+			It("extracts and reports the time spent syncing", fsyncTest)
+
+			It("does not sync by default", noFsyncTest)
+
+			It("syncs atomic extractions", atomicFsyncTest)
+		})
+
+		Context("with a result", func() {
+			BeforeEach(createResultArchive)
+
+			It("describes what was extracted, skipped and rewritten", resultTest)
+
+			It("records the parent directories it creates, and not those that existed", resultParentDirsTest)
+
+			It("reports symlinks skipped by policy", resultSkippedSymlinkTest)
+		})
+
+		Context("with modification times", func() {
+			BeforeEach(func() {
+				test_helper.CreateZipArchive(extractionSrc, timedArchiveFiles)
+			})
+
+			It("restores them once the directories' contents are written", timesTest)
+		})
+
+		Context("with read-only directories", func() {
+			BeforeEach(func() {
+				test_helper.CreateZipArchive(extractionSrc, readOnlyArchiveFiles)
+			})
+
+			It("writes their contents before making them read-only", readOnlyTest)
+		})
+
 		Context("with a bad zip archive", func() {
 			BeforeEach(func() {
 				test_helper.CreateZipArchive(extractionSrc, []test_helper.ArchiveFile{
@@ -137,11 +604,85 @@ var _ = Describe("Extractor", func() {
 
 	Context("when the file is a tgz archive", func() {
 		BeforeEach(func() {
-			test_helper.CreateTarGZArchive(extractionSrc, archiveFiles)
+			newExtractor = NewTgz
+			createArchive = test_helper.CreateTarGZArchive
+			createArchive(extractionSrc, archiveFiles)
 		})
 
 		It("extracts the TGZ's files, generating directories, and honoring file permissions and symlinks", extractionTest)
 
+		It("stops extracting when the context is cancelled", cancellationTest)
+
+		It("extracts from a stream", readerExtractionTest)
+
+		Context("when the context is cancelled mid-stream", func() {
+			BeforeEach(func() {
+				test_helper.CreateTarGZArchive(extractionSrc, partialArchiveFiles)
+			})
+
+			It("removes what it extracted", streamCancellationTest)
+		})
+
+		Context("with strict paths", func() {
+			DescribeTable("rejects entries naming", strictPathsTest, badNames)
+
+			It("extracts entries that stay inside the destination", strictPathsInsideTest)
+		})
+
+		Context("with existing paths", func() {
+			BeforeEach(createConflicts)
+
+			It("overwrites them by default, replacing files with symlinks", conflictOverwriteTest)
+
+			It("replaces symlinks rather than writing through them", conflictSymlinkTest)
+
+			It("keeps them with ConflictSkip", conflictSkipTest)
+
+			It("keeps only those newer than their entries with ConflictKeepNewer", conflictKeepNewerTest)
+
+			It("fails with ConflictError", conflictErrorTest)
+
+			DescribeTable("merges directories into them, keeping their metadata", conflictMergeTest, mergePolicies)
+		})
+
+		Context("with fsync", func() {
+			BeforeEach(func() {
+				test_helper.CreateTarGZArchive(extractionSrc, syncedArchiveFiles)
+			})
+
+			It("extracts and reports the time spent syncing", fsyncTest)
+
+			It("does not sync by default", noFsyncTest)
+
+			It("syncs atomic extractions", atomicFsyncTest)
+		})
+
+		Context("with a result", func() {
+			BeforeEach(createResultArchive)
+
+			It("describes what was extracted, skipped and rewritten", resultTest)
+
+			It("records the parent directories it creates, and not those that existed", resultParentDirsTest)
+
+			It("reports symlinks skipped by policy", resultSkippedSymlinkTest)
+		})
+
+		Context("with modification times", func() {
+			BeforeEach(func() {
+				test_helper.CreateTarGZArchive(extractionSrc, timedArchiveFiles)
+			})
+
+			It("restores them once the directories' contents are written", timesTest)
+		})
+
+		Context("with read-only directories", func() {
+			BeforeEach(func() {
+				test_helper.CreateTarGZArchive(extractionSrc, readOnlyArchiveFiles)
+			})
+
+			It("writes their contents before making them read-only", readOnlyTest)
+		})
+
 		Context("with a bad tgz archive", func() {
 			BeforeEach(func() {
 				test_helper.CreateTarGZArchive(extractionSrc, []test_helper.ArchiveFile{
@@ -166,11 +707,152 @@ var _ = Describe("Extractor", func() {
 	Context("when the file is a tar archive", func() {
 		BeforeEach(func() {
 			extractor = NewTar()
-			test_helper.CreateTarArchive(extractionSrc, archiveFiles)
+			newExtractor = NewTar
+			createArchive = test_helper.CreateTarArchive
+			createArchive(extractionSrc, archiveFiles)
 		})
 
 		It("extracts the TAR's files, generating directories, and honoring file permissions and symlinks", extractionTest)
 
+		Context("with the detectable extractor", func() {
+			BeforeEach(func() {
+				extractor = NewDetectable()
+			})
+
+			It("detects the TAR and extracts its files", extractionTest)
+
+			It("detects the TAR from a stream", readerExtractionTest)
+		})
+
+		It("stops extracting when the context is cancelled", cancellationTest)
+
+		It("extracts from a stream", readerExtractionTest)
+
+		Context("when the context is cancelled mid-stream", func() {
+			BeforeEach(func() {
+				test_helper.CreateTarArchive(extractionSrc, partialArchiveFiles)
+			})
+
+			It("removes what it extracted", streamCancellationTest)
+		})
+
+		Context("with strict paths", func() {
+			DescribeTable("rejects entries naming", strictPathsTest, badNames)
+
+			It("extracts entries that stay inside the destination", strictPathsInsideTest)
+		})
+
+		Context("with existing paths", func() {
+			BeforeEach(createConflicts)
+
+			It("overwrites them by default, replacing files with symlinks", conflictOverwriteTest)
+
+			It("replaces symlinks rather than writing through them", conflictSymlinkTest)
+
+			It("keeps them with ConflictSkip", conflictSkipTest)
+
+			It("keeps only those newer than their entries with ConflictKeepNewer", conflictKeepNewerTest)
+
+			It("fails with ConflictError", conflictErrorTest)
+
+			DescribeTable("merges directories into them, keeping their metadata", conflictMergeTest, mergePolicies)
+		})
+
+		Context("with fsync", func() {
+			BeforeEach(func() {
+				test_helper.CreateTarArchive(extractionSrc, syncedArchiveFiles)
+			})
+
+			It("extracts and reports the time spent syncing", fsyncTest)
+
+			It("does not sync by default", noFsyncTest)
+
+			It("syncs atomic extractions", atomicFsyncTest)
+		})
+
+		Context("with a result", func() {
+			BeforeEach(createResultArchive)
+
+			It("describes what was extracted, skipped and rewritten", resultTest)
+
+			It("records the parent directories it creates, and not those that existed", resultParentDirsTest)
+
+			It("reports symlinks skipped by policy", resultSkippedSymlinkTest)
+		})
+
+		Context("with modification times", func() {
+			BeforeEach(func() {
+				test_helper.CreateTarArchive(extractionSrc, timedArchiveFiles)
+			})
+
+			It("restores them once the directories' contents are written", timesTest)
+		})
+
+		Context("with read-only directories", func() {
+			BeforeEach(func() {
+				test_helper.CreateTarArchive(extractionSrc, readOnlyArchiveFiles)
+			})
+
+			It("writes their contents before making them read-only", readOnlyTest)
+		})
+
+		Context("with a hard link", func() {
+			BeforeEach(func() {
+				test_helper.CreateTarArchive(extractionSrc, []test_helper.ArchiveFile{
+					{
+						Name: "some-file",
+						Body: "some-file-contents",
+					},
+					{
+						Name:     "nested/some-hard-link",
+						HardLink: "some-file",
+					},
+				})
+			})
+
+			It("links the entry to the file already extracted", func() {
+				err := extractor.Extract(extractionSrc, extractionDest)
+				Expect(err).NotTo(HaveOccurred())
+
+				fileInfo, err := os.Stat(filepath.Join(extractionDest, "some-file"))
+				Expect(err).NotTo(HaveOccurred())
+
+				linkInfo, err := os.Stat(filepath.Join(extractionDest, "nested", "some-hard-link"))
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(os.SameFile(fileInfo, linkInfo)).To(BeTrue())
+			})
+		})
+
+		Context("with a hard link pointing outside of the destination", func() {
+			BeforeEach(func() {
+				test_helper.CreateTarArchive(extractionSrc, []test_helper.ArchiveFile{
+					{
+						Name:     "some-hard-link",
+						HardLink: "../some-file",
+					},
+				})
+			})
+
+			It("returns an error", func() {
+				subdir := filepath.Join(extractionDest, "subdir")
+				Expect(os.Mkdir(subdir, 0777)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(extractionDest, "some-file"), []byte("secret"), 0600)).To(Succeed())
+
+				err := extractor.Extract(extractionSrc, subdir)
+				Expect(err).To(MatchError(ContainSubstring("points outside of the destination")))
+				Expect(filepath.Join(subdir, "some-hard-link")).NotTo(BeAnExistingFile())
+			})
+
+			It("returns a *PathTraversalError with strict paths", func() {
+				err := NewTar(WithStrictPaths()).Extract(extractionSrc, extractionDest)
+
+				var traversalErr *PathTraversalError
+				Expect(errors.As(err, &traversalErr)).To(BeTrue(), "expected a *PathTraversalError, got %v", err)
+				Expect(traversalErr.Name).To(Equal("some-hard-link"))
+			})
+		})
+
 		Context("with a bad tar archive", func() {
 			BeforeEach(func() {
 				test_helper.CreateTarArchive(extractionSrc, []test_helper.ArchiveFile{
@@ -191,4 +873,287 @@ var _ = Describe("Extractor", func() {
 			})
 		})
 	})
+
+	Context("when the file is a GNU format tar archive", func() {
+		BeforeEach(func() {
+			file, err := os.Create(extractionSrc)
+			Expect(err).NotTo(HaveOccurred())
+
+			w := tar.NewWriter(file)
+			err = w.WriteHeader(&tar.Header{
+				Name:   "some-file",
+				Mode:   0644,
+				Size:   int64(len("some-file-contents")),
+				Format: tar.FormatGNU,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = w.Write([]byte("some-file-contents"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(w.Close()).To(Succeed())
+			Expect(file.Close()).To(Succeed())
+		})
+
+		It("detects and extracts it", func() {
+			err := extractor.Extract(extractionSrc, extractionDest)
+			Expect(err).NotTo(HaveOccurred())
+
+			fileContents, err := os.ReadFile(filepath.Join(extractionDest, "some-file"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(fileContents)).To(Equal("some-file-contents"))
+		})
+	})
+
+	Context("when the file is empty", func() {
+		It("returns an unsupported format error", func() {
+			err := extractor.Extract(extractionSrc, extractionDest)
+			Expect(err).To(MatchError(ErrUnsupportedFormat))
+
+			var formatErr *UnsupportedFormatError
+			Expect(errors.As(err, &formatErr)).To(BeTrue())
+			Expect(formatErr.Signature).To(Equal("empty"))
+		})
+
+		It("does not create the destination when reading a stream", func() {
+			dest := filepath.Join(extractionDest, "dest")
+
+			err := extractor.ExtractReader(strings.NewReader(""), dest)
+			Expect(err).To(MatchError(ErrUnsupportedFormat))
+			Expect(dest).NotTo(BeADirectory())
+		})
+	})
+
+	Context("when the file is shorter than a tar block and not an archive", func() {
+		BeforeEach(func() {
+			Expect(os.WriteFile(extractionSrc, []byte("not an archive"), 0644)).To(Succeed())
+		})
+
+		It("returns an unsupported format error carrying the signature", func() {
+			err := extractor.Extract(extractionSrc, extractionDest)
+			Expect(err).To(MatchError(ErrUnsupportedFormat))
+
+			var formatErr *UnsupportedFormatError
+			Expect(errors.As(err, &formatErr)).To(BeTrue())
+			Expect(formatErr.Src).To(Equal(extractionSrc))
+			Expect(formatErr.Signature).To(Equal("6e6f7420616e2061"))
+		})
+	})
+
+	Context("when the file is a bzip2 stream", func() {
+		BeforeEach(func() {
+			Expect(os.WriteFile(extractionSrc, []byte("BZh91AY&SY"), 0644)).To(Succeed())
+		})
+
+		It("names the format in the unsupported format error", func() {
+			err := extractor.Extract(extractionSrc, extractionDest)
+
+			var formatErr *UnsupportedFormatError
+			Expect(errors.As(err, &formatErr)).To(BeTrue())
+			Expect(formatErr.Signature).To(Equal("bzip2"))
+		})
+
+		Context("when a bzip2 format is registered", func() {
+			var registered *fake_extractor.FakeExtractor
+
+			BeforeEach(func() {
+				registered = &fake_extractor.FakeExtractor{}
+				Register("test-bzip2", func(header []byte) bool {
+					return strings.HasPrefix(string(header), "BZh")
+				}, func(...Option) Extractor {
+					return registered
+				})
+			})
+
+			AfterEach(func() {
+				Unregister("test-bzip2")
+			})
+
+			It("extracts it with the registered extractor", func() {
+				err := extractor.Extract(extractionSrc, extractionDest)
+				Expect(err).NotTo(HaveOccurred())
+
+				src, dest := registered.ExtractInput()
+				Expect(src).To(Equal(extractionSrc))
+				Expect(dest).To(Equal(extractionDest))
+			})
+		})
+	})
+
+	Context("when a registered extractor can only extract files", func() {
+		var registered *fileOnlyExtractor
+
+		BeforeEach(func() {
+			Expect(os.WriteFile(extractionSrc, []byte("BZh91AY&SY"), 0644)).To(Succeed())
+
+			registered = &fileOnlyExtractor{}
+			Register("test-bzip2", func(header []byte) bool {
+				return strings.HasPrefix(string(header), "BZh")
+			}, func(...Option) Extractor {
+				return registered
+			})
+		})
+
+		AfterEach(func() {
+			Unregister("test-bzip2")
+		})
+
+		It("extracts streams through a temporary file", func() {
+			err := extractor.ExtractReaderContext(context.Background(), strings.NewReader("BZh91AY&SY"), extractionDest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(registered.contents).To(Equal("BZh91AY&SY"))
+			Expect(registered.dest).To(Equal(extractionDest))
+		})
+
+		It("does not start once the context is cancelled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			err := extractor.ExtractContext(ctx, extractionSrc, extractionDest)
+			Expect(err).To(MatchError(context.Canceled))
+			Expect(registered.dest).To(BeEmpty())
+		})
+	})
+
+	Context("when a format overlapping a built-in one is registered", func() {
+		var registered *fake_extractor.FakeExtractor
+
+		BeforeEach(func() {
+			test_helper.CreateTarGZArchive(extractionSrc, archiveFiles)
+
+			registered = &fake_extractor.FakeExtractor{}
+		})
+
+		AfterEach(func() {
+			Unregister("test-gzip")
+		})
+
+		It("matches the built-in format first at the default priority", func() {
+			Register("test-gzip", func(header []byte) bool {
+				return strings.HasPrefix(string(header), "\x1f\x8b")
+			}, func(...Option) Extractor {
+				return registered
+			})
+
+			err := extractor.Extract(extractionSrc, extractionDest)
+			Expect(err).NotTo(HaveOccurred())
+
+			src, _ := registered.ExtractInput()
+			Expect(src).To(BeEmpty())
+		})
+
+		It("matches the registered format first at a higher priority", func() {
+			RegisterPriority("test-gzip", DefaultPriority+1, func(header []byte) bool {
+				return strings.HasPrefix(string(header), "\x1f\x8b")
+			}, func(...Option) Extractor {
+				return registered
+			})
+
+			err := extractor.Extract(extractionSrc, extractionDest)
+			Expect(err).NotTo(HaveOccurred())
+
+			src, _ := registered.ExtractInput()
+			Expect(src).To(Equal(extractionSrc))
+		})
+
+		It("orders the most extreme priorities", func() {
+			RegisterPriority("test-never", math.MinInt, func([]byte) bool {
+				return false
+			}, nil)
+			DeferCleanup(Unregister, "test-never")
+
+			RegisterPriority("test-gzip", math.MaxInt, func(header []byte) bool {
+				return strings.HasPrefix(string(header), "\x1f\x8b")
+			}, func(...Option) Extractor {
+				return registered
+			})
+
+			err := extractor.Extract(extractionSrc, extractionDest)
+			Expect(err).NotTo(HaveOccurred())
+
+			src, _ := registered.ExtractInput()
+			Expect(src).To(Equal(extractionSrc))
+		})
+
+		It("calls the factory without holding the registry", func() {
+			RegisterPriority("test-gzip", DefaultPriority+1, func(header []byte) bool {
+				return strings.HasPrefix(string(header), "\x1f\x8b")
+			}, func(...Option) Extractor {
+				Register("test-nested", func([]byte) bool { return false }, nil)
+				return registered
+			})
+			DeferCleanup(Unregister, "test-nested")
+
+			done := make(chan error)
+			go func() {
+				done <- NewDetectable().Extract(extractionSrc, extractionDest)
+			}()
+
+			Eventually(done).Should(Receive(Succeed()))
+		})
+	})
 })
+
+// fileOnlyExtractor implements nothing beyond Extractor.
+type fileOnlyExtractor struct {
+	contents string
+	dest     string
+}
+
+func (e *fileOnlyExtractor) Extract(src, dest string) error {
+	contents, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	e.contents = string(contents)
+	e.dest = dest
+	return nil
+}
+
+// tempArchive returns an empty archive file, removed after the spec.
+func tempArchive() string {
+	archive, err := os.CreateTemp("", "extractor-archive")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(archive.Close()).To(Succeed())
+	DeferCleanup(os.RemoveAll, archive.Name())
+	return archive.Name()
+}
+
+// tempDest returns an empty destination directory, removed after the spec.
+func tempDest() string {
+	dest, err := os.MkdirTemp("", "extracted")
+	Expect(err).NotTo(HaveOccurred())
+	DeferCleanup(os.RemoveAll, dest)
+	return dest
+}
+
+type streamReader struct {
+	r io.Reader
+}
+
+func (s streamReader) Read(p []byte) (int, error) {
+	return s.r.Read(p)
+}
+
+type cancellingReader struct {
+	r      io.Reader
+	reads  int
+	cancel func()
+}
+
+func (c *cancellingReader) Read(p []byte) (int, error) {
+	c.reads++
+	if c.reads > 1 {
+		c.cancel()
+	}
+	return c.r.Read(p)
+}
+
+func randomText(chunks int) string {
+	var text strings.Builder
+	for range chunks {
+		text.WriteString(rand.Text())
+	}
+	return text.String()
+}
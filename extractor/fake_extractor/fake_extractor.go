@@ -1,24 +1,63 @@
 package fake_extractor
 
+import (
+	"context"
+	"io"
+
+	"code.cloudfoundry.org/archiver/extractor"
+)
+
 type FakeExtractor struct {
 	extractInput struct {
-		src  string
-		dest string
+		src    string
+		reader io.Reader
+		dest   string
 	}
 	extractOutput struct {
-		err error
+		result extractor.ExtractResult
+		err    error
 	}
 }
 
-func (extractor *FakeExtractor) Extract(src, dest string) error {
-	extractor.extractInput.src = src
-	extractor.extractInput.dest = dest
-	return extractor.extractOutput.err
+func (fake *FakeExtractor) Extract(src, dest string) error {
+	return fake.ExtractContext(context.Background(), src, dest)
+}
+
+func (fake *FakeExtractor) ExtractContext(ctx context.Context, src, dest string) error {
+	_, err := fake.ExtractWithResult(ctx, src, dest)
+	return err
+}
+
+func (fake *FakeExtractor) ExtractWithResult(ctx context.Context, src, dest string) (extractor.ExtractResult, error) {
+	fake.extractInput.src = src
+	fake.extractInput.dest = dest
+	return fake.extractOutput.result, fake.extractOutput.err
+}
+
+func (fake *FakeExtractor) ExtractReader(r io.Reader, dest string) error {
+	return fake.ExtractReaderContext(context.Background(), r, dest)
+}
+
+func (fake *FakeExtractor) ExtractReaderContext(ctx context.Context, r io.Reader, dest string) error {
+	_, err := fake.ExtractReaderWithResult(ctx, r, dest)
+	return err
 }
 
-func (extractor *FakeExtractor) ExtractInput() (src, dest string) {
-	return extractor.extractInput.src, extractor.extractInput.dest
+func (fake *FakeExtractor) ExtractReaderWithResult(ctx context.Context, r io.Reader, dest string) (extractor.ExtractResult, error) {
+	fake.extractInput.reader = r
+	fake.extractInput.dest = dest
+	return fake.extractOutput.result, fake.extractOutput.err
+}
+
+func (fake *FakeExtractor) ExtractInput() (src, dest string) {
+	return fake.extractInput.src, fake.extractInput.dest
+}
+func (fake *FakeExtractor) ExtractReaderInput() (r io.Reader, dest string) {
+	return fake.extractInput.reader, fake.extractInput.dest
+}
+func (fake *FakeExtractor) SetExtractOutput(err error) {
+	fake.extractOutput.err = err
 }
-func (extractor *FakeExtractor) SetExtractOutput(err error) {
-	extractor.extractOutput.err = err
+func (fake *FakeExtractor) SetExtractResult(result extractor.ExtractResult) {
+	fake.extractOutput.result = result
 }
@@ -0,0 +1,107 @@
+package extractor
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// ExtractLimits bounds the resources an archive can consume on extraction,
+// so that a small hostile archive cannot fill a disk. Zero fields are not
+// enforced.
+type ExtractLimits struct {
+	// MaxTotalBytes caps the bytes written across all files.
+	MaxTotalBytes int64
+	// MaxEntries caps the number of entries extracted.
+	MaxEntries int
+	// MaxFileBytes caps the bytes written to any one file.
+	MaxFileBytes int64
+	// MaxRatio caps the bytes written per byte of archive read so far.
+	MaxRatio float64
+	// MaxNameLength caps the length of entry names, in bytes.
+	MaxNameLength int
+	// MaxDepth caps the number of path elements in entry names.
+	MaxDepth int
+}
+
+// LimitError reports the entry that took extraction past one of its
+// ExtractLimits, named by its field, such as "MaxTotalBytes".
+type LimitError struct {
+	Limit string
+	Name  string
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("archive entry %q exceeds the %s limit", e.Name, e.Limit)
+}
+
+// startEntry counts an entry and checks its name, before anything is written
+// for it.
+func (x *extraction) startEntry(name, path string) error {
+	x.entry = name
+	x.entries++
+
+	limits := x.opts.limits
+	switch {
+	case limits.MaxEntries > 0 && x.entries > limits.MaxEntries:
+		return &LimitError{Limit: "MaxEntries", Name: name}
+	case limits.MaxNameLength > 0 && len(name) > limits.MaxNameLength:
+		return &LimitError{Limit: "MaxNameLength", Name: name}
+	case limits.MaxDepth > 0 && strings.Count(path, string(filepath.Separator))+1 > limits.MaxDepth:
+		return &LimitError{Limit: "MaxDepth", Name: name}
+	}
+
+	return nil
+}
+
+// limitWriter fails a write that would take the current entry past the size
+// limits, before any of it reaches w.
+type limitWriter struct {
+	x       *extraction
+	w       io.Writer
+	written int64
+}
+
+func (w *limitWriter) Write(p []byte) (int, error) {
+	x := w.x
+	limits := x.opts.limits
+
+	w.written += int64(len(p))
+	x.written += int64(len(p))
+
+	switch {
+	case limits.MaxFileBytes > 0 && w.written > limits.MaxFileBytes:
+		return 0, &LimitError{Limit: "MaxFileBytes", Name: x.entry}
+	case limits.MaxTotalBytes > 0 && x.written > limits.MaxTotalBytes:
+		return 0, &LimitError{Limit: "MaxTotalBytes", Name: x.entry}
+	case limits.MaxRatio > 0 && x.archiveBytes != nil && float64(x.written) > limits.MaxRatio*float64(x.archiveBytes()):
+		return 0, &LimitError{Limit: "MaxRatio", Name: x.entry}
+	}
+
+	return w.w.Write(p)
+}
+
+// countingReader counts the archive bytes read, for MaxRatio.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// countingReaderAt counts the archive bytes read through it, for MaxRatio.
+type countingReaderAt struct {
+	r io.ReaderAt
+	n int64
+}
+
+func (r *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := r.r.ReadAt(p, off)
+	r.n += int64(n)
+	return n, err
+}
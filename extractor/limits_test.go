@@ -0,0 +1,131 @@
+package extractor_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "code.cloudfoundry.org/archiver/extractor"
+	"code.cloudfoundry.org/archiver/extractor/test_helper"
+)
+
+var _ = Describe("Limits", func() {
+	var extractionDest string
+	var extractionSrc string
+
+	BeforeEach(func() {
+		extractionDest = tempDest()
+		extractionSrc = tempArchive()
+	})
+
+	expectLimitError := func(err error, limit, name string) {
+		var limitErr *LimitError
+		Expect(errors.As(err, &limitErr)).To(BeTrue(), "expected a *LimitError, got %v", err)
+		Expect(limitErr.Limit).To(Equal(limit))
+		Expect(limitErr.Name).To(Equal(name))
+	}
+
+	DescribeTable("failing a tgz archive that exceeds a limit",
+		func(limits ExtractLimits, files []test_helper.ArchiveFile, limit, name string) {
+			test_helper.CreateTarGZArchive(extractionSrc, files)
+
+			err := NewTgz(WithLimits(limits)).Extract(extractionSrc, extractionDest)
+			expectLimitError(err, limit, name)
+		},
+		Entry("MaxTotalBytes",
+			ExtractLimits{MaxTotalBytes: 1000},
+			[]test_helper.ArchiveFile{
+				{Name: "first-file", Body: strings.Repeat("a", 600)},
+				{Name: "second-file", Body: strings.Repeat("b", 600)},
+			},
+			"MaxTotalBytes", "second-file",
+		),
+		Entry("MaxEntries",
+			ExtractLimits{MaxEntries: 2},
+			[]test_helper.ArchiveFile{
+				{Name: "some-dir/", Dir: true},
+				{Name: "some-dir/first-file", Body: "first-file-contents"},
+				{Name: "some-dir/second-file", Body: "second-file-contents"},
+			},
+			"MaxEntries", "some-dir/second-file",
+		),
+		Entry("MaxFileBytes",
+			ExtractLimits{MaxFileBytes: 500},
+			[]test_helper.ArchiveFile{
+				{Name: "small-file", Body: strings.Repeat("a", 500)},
+				{Name: "big-file", Body: strings.Repeat("b", 501)},
+			},
+			"MaxFileBytes", "big-file",
+		),
+		Entry("MaxRatio",
+			ExtractLimits{MaxRatio: 100},
+			[]test_helper.ArchiveFile{
+				{Name: "zeroes", Body: strings.Repeat("\x00", 1<<20)},
+			},
+			"MaxRatio", "zeroes",
+		),
+		Entry("MaxNameLength",
+			ExtractLimits{MaxNameLength: 64},
+			[]test_helper.ArchiveFile{
+				{Name: strings.Repeat("n", 65), Body: "long-name-contents"},
+			},
+			"MaxNameLength", strings.Repeat("n", 65),
+		),
+		Entry("MaxDepth",
+			ExtractLimits{MaxDepth: 3},
+			[]test_helper.ArchiveFile{
+				{Name: "a/b/c", Body: "shallow-contents"},
+				{Name: "a/b/c/d", Body: "deep-contents"},
+			},
+			"MaxDepth", "a/b/c/d",
+		),
+	)
+
+	It("extracts archives within the limits", func() {
+		test_helper.CreateTarGZArchive(extractionSrc, []test_helper.ArchiveFile{
+			{Name: "some-dir/", Dir: true},
+			{Name: "some-dir/some-file", Body: randomText(16)},
+		})
+
+		err := NewTgz(WithLimits(ExtractLimits{
+			MaxTotalBytes: 1 << 20,
+			MaxEntries:    2,
+			MaxFileBytes:  1 << 20,
+			MaxRatio:      10,
+			MaxNameLength: 64,
+			MaxDepth:      2,
+		})).Extract(extractionSrc, extractionDest)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("limits the compression ratio of zip archives", func() {
+		test_helper.CreateZipArchive(extractionSrc, []test_helper.ArchiveFile{
+			{Name: "zeroes", Body: strings.Repeat("\x00", 1<<20)},
+		})
+
+		err := NewZip(WithLimits(ExtractLimits{MaxRatio: 100})).Extract(extractionSrc, extractionDest)
+		expectLimitError(err, "MaxRatio", "zeroes")
+	})
+
+	It("limits the compression ratio of zip archives that misstate their compressed sizes", func() {
+		test_helper.CreateZipArchive(extractionSrc, []test_helper.ArchiveFile{
+			{Name: "zeroes", Body: strings.Repeat("\x00", 1<<22)},
+		})
+
+		archive, err := os.ReadFile(extractionSrc)
+		Expect(err).NotTo(HaveOccurred())
+
+		centralDirectory := bytes.LastIndex(archive, []byte("PK\x01\x02"))
+		Expect(centralDirectory).To(BeNumerically(">", 0))
+		binary.LittleEndian.PutUint32(archive[centralDirectory+20:], 0xFFFFFFF0)
+		Expect(os.WriteFile(extractionSrc, archive, 0644)).To(Succeed())
+
+		err = NewZip(WithLimits(ExtractLimits{MaxRatio: 10})).Extract(extractionSrc, extractionDest)
+		expectLimitError(err, "MaxRatio", "zeroes")
+	})
+})
@@ -0,0 +1,37 @@
+package extractor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// mknodat creates the node in a private temporary directory and renames it
+// into dirfd, since there is no mknodat here and a path through the
+// destination could be redirected by a symlink swapped in meanwhile. A node
+// that cannot be renamed across file systems is unsupported.
+func mknodat(dirfd int, name string, mode uint32, dev uint64) error {
+	tmp, err := os.MkdirTemp("", "archiver-node")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	node := filepath.Join(tmp, "node")
+	if mode&unix.S_IFMT == unix.S_IFIFO {
+		err = unix.Mkfifo(node, mode&^unix.S_IFMT)
+	} else {
+		err = unix.Mknod(node, mode, int(dev))
+	}
+	if err != nil {
+		return err
+	}
+
+	err = unix.Renameat(unix.AT_FDCWD, node, dirfd, name)
+	if errors.Is(err, unix.EXDEV) {
+		return errors.ErrUnsupported
+	}
+	return err
+}
@@ -0,0 +1,9 @@
+//go:build unix && !darwin && !freebsd
+
+package extractor
+
+import "golang.org/x/sys/unix"
+
+func mknodat(dirfd int, name string, mode uint32, dev uint64) error {
+	return unix.Mknodat(dirfd, name, mode, int(dev))
+}
@@ -0,0 +1,269 @@
+package extractor
+
+import (
+	"os"
+	"strings"
+
+	"code.cloudfoundry.org/archiver/idmap"
+)
+
+type Option func(*options)
+
+type options struct {
+	strictPaths  bool
+	strictXattrs bool
+	xattrAllow   []string
+	symlinks     SymlinkPolicy
+	limits       ExtractLimits
+	devices      bool
+	conflicts    ConflictPolicy
+	atomic       bool
+	fsync        bool
+	unsupported  UnsupportedEntryPolicy
+
+	processUmask os.FileMode
+	umask        os.FileMode
+	umaskSet     bool
+	exactModes   bool
+	fixedModes   bool
+	fileMode     os.FileMode
+	dirMode      os.FileMode
+	setuidSetgid bool
+
+	ownership  bool
+	uidMap     idmap.Mapping
+	gidMap     idmap.Mapping
+	forceOwner bool
+	uid, gid   int
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithStrictPaths fails extraction with a *PathTraversalError on any entry
+// whose name, or hard link target, is absolute, names a Windows drive or
+// share, or contains "..", rather than extracting it inside the destination
+// regardless.
+func WithStrictPaths() Option {
+	return func(o *options) {
+		o.strictPaths = true
+	}
+}
+
+// WithSymlinkPolicy decides what to do with symlinks whose targets are
+// absolute or escape the destination. SymlinkAllow is the default.
+func WithSymlinkPolicy(policy SymlinkPolicy) Option {
+	return func(o *options) {
+		o.symlinks = policy
+	}
+}
+
+// WithLimits fails extraction with a *LimitError as soon as the archive
+// exceeds any of the limits.
+func WithLimits(limits ExtractLimits) Option {
+	return func(o *options) {
+		o.limits = limits
+	}
+}
+
+// WithDevices creates the character and block devices in tar archives,
+// which needs privilege. They are unsupported entries otherwise.
+func WithDevices() Option {
+	return func(o *options) {
+		o.devices = true
+	}
+}
+
+// WithUnsupportedEntries decides what happens to tar entries that cannot be
+// extracted here, such as devices, or types this package does not know.
+// UnsupportedSkip is the default.
+func WithUnsupportedEntries(policy UnsupportedEntryPolicy) Option {
+	return func(o *options) {
+		o.unsupported = policy
+	}
+}
+
+// WithConflictPolicy decides what happens when an entry's path already
+// exists in the destination. ConflictOverwrite is the default.
+func WithConflictPolicy(policy ConflictPolicy) Option {
+	return func(o *options) {
+		o.conflicts = policy
+	}
+}
+
+// WithAtomic extracts into a staging directory beside dest and moves it into
+// place, replacing anything already at dest, only once extraction succeeds.
+// On failure or cancellation dest is left as it was.
+func WithAtomic() Option {
+	return func(o *options) {
+		o.atomic = true
+	}
+}
+
+// WithFsync flushes every extracted file to stable storage, and then every
+// directory extraction wrote into, before it returns. The time this takes is
+// reported as ExtractResult.SyncDuration.
+func WithFsync() Option {
+	return func(o *options) {
+		o.fsync = true
+	}
+}
+
+// WithStrictXattrs fails extraction when an extended attribute cannot be
+// applied, rather than skipping it because the filesystem does not support
+// it (ENOTSUP) or the process may not set it (EPERM).
+func WithStrictXattrs() Option {
+	return func(o *options) {
+		o.strictXattrs = true
+	}
+}
+
+// WithXattrAllow applies only the extended attributes in the given
+// namespaces, such as "user" for unprivileged extraction. All are applied by
+// default.
+func WithXattrAllow(namespaces ...string) Option {
+	return func(o *options) {
+		o.xattrAllow = append(o.xattrAllow, namespaces...)
+	}
+}
+
+// WithUmask clears the given permission bits from every extracted file and
+// directory, in place of the process umask, which is applied by default.
+func WithUmask(mask os.FileMode) Option {
+	return func(o *options) {
+		o.umask = mask & os.ModePerm
+		o.umaskSet = true
+	}
+}
+
+// WithExactPermissions applies archived modes exactly, whatever the process
+// umask. Zip entries that record no unix mode are still masked.
+func WithExactPermissions() Option {
+	return func(o *options) {
+		o.exactModes = true
+	}
+}
+
+// WithFixedPermissions ignores the archived modes and gives every extracted
+// file fileMode and every directory dirMode, which only WithUmask masks.
+func WithFixedPermissions(fileMode, dirMode os.FileMode) Option {
+	return func(o *options) {
+		o.fixedModes = true
+		o.fileMode = fileMode
+		o.dirMode = dirMode
+	}
+}
+
+// WithSetuidSetgid keeps the setuid and setgid bits of archived modes, which
+// are dropped by default.
+func WithSetuidSetgid() Option {
+	return func(o *options) {
+		o.setuidSetgid = true
+	}
+}
+
+// WithOwnership gives extracted entries the owner recorded in the archive.
+// Zip archives record no owner, so their entries keep the extracting user's.
+func WithOwnership() Option {
+	return func(o *options) {
+		o.ownership = true
+	}
+}
+
+// WithUIDMap restores ownership with the archived user IDs mapped from a
+// container's user namespace to the host. IDs the mapping does not cover
+// become idmap.OverflowID.
+func WithUIDMap(m idmap.Mapping) Option {
+	return func(o *options) {
+		o.ownership = true
+		o.uidMap = m
+	}
+}
+
+// WithGIDMap restores ownership with the archived group IDs mapped from a
+// container's user namespace to the host, like WithUIDMap.
+func WithGIDMap(m idmap.Mapping) Option {
+	return func(o *options) {
+		o.ownership = true
+		o.gidMap = m
+	}
+}
+
+// WithOwner gives every extracted entry the given host user and group,
+// whatever the archive records.
+func WithOwner(uid, gid int) Option {
+	return func(o *options) {
+		o.ownership = true
+		o.forceOwner = true
+		o.uid, o.gid = uid, gid
+	}
+}
+
+// hostOwner returns the host IDs to give an entry archived with uid and gid,
+// which are -1 when the archive records none.
+func (o *options) hostOwner(uid, gid int) (int, int) {
+	if o.forceOwner {
+		return o.uid, o.gid
+	}
+
+	if uid >= 0 && o.uidMap != nil {
+		uid = o.uidMap.ToHost(uid)
+	}
+
+	if gid >= 0 && o.gidMap != nil {
+		gid = o.gidMap.ToHost(gid)
+	}
+
+	return uid, gid
+}
+
+// permissions returns the permission and special bits to apply to an
+// extracted file or directory with the archived mode.
+func (o *options) permissions(mode os.FileMode) os.FileMode {
+	if o.fixedModes {
+		if mode.IsDir() {
+			mode = o.dirMode
+		} else {
+			mode = o.fileMode
+		}
+	}
+
+	mode &= os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+	if o.umaskSet || (!o.exactModes && !o.fixedModes) {
+		mode &^= o.mask()
+	}
+	if !o.setuidSetgid {
+		mode &^= os.ModeSetuid | os.ModeSetgid
+	}
+
+	return mode
+}
+
+// mask returns the permission bits to clear from archived modes.
+func (o *options) mask() os.FileMode {
+	if o.umaskSet {
+		return o.umask
+	}
+
+	return o.processUmask
+}
+
+func (o *options) appliesXattr(name string) bool {
+	if len(o.xattrAllow) == 0 {
+		return true
+	}
+
+	for _, namespace := range o.xattrAllow {
+		if name == namespace || strings.HasPrefix(name, namespace+".") {
+			return true
+		}
+	}
+
+	return false
+}
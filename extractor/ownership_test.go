@@ -0,0 +1,149 @@
+package extractor_test
+
+import (
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "code.cloudfoundry.org/archiver/extractor"
+	"code.cloudfoundry.org/archiver/extractor/test_helper"
+	"code.cloudfoundry.org/archiver/idmap"
+)
+
+var _ = Describe("Ownership", func() {
+	type owner struct {
+		uid, gid int
+	}
+
+	var extractionDest string
+	var extractionSrc string
+	var owners map[string]owner
+
+	archiveFiles := []test_helper.ArchiveFile{
+		{
+			Name: "some-dir/",
+			Dir:  true,
+			Uid:  0,
+			Gid:  0,
+		},
+		{
+			Name: "some-dir/some-file",
+			Body: "some-file-contents",
+			Uid:  1000,
+			Gid:  1001,
+		},
+		{
+			Name: "some-symlink",
+			Link: "some-dir/some-file",
+			Uid:  1000,
+			Gid:  1001,
+		},
+		{
+			Name:     "some-hard-link",
+			HardLink: "some-dir/some-file",
+			Uid:      1000,
+			Gid:      1001,
+		},
+		{
+			Name: "nobody-file",
+			Body: "nobody-file-contents",
+			Uid:  70000,
+			Gid:  70000,
+		},
+	}
+
+	BeforeEach(func() {
+		extractionDest = tempDest()
+		extractionSrc = tempArchive()
+
+		owners = map[string]owner{}
+		DeferCleanup(SetLchown(func(name string, uid, gid int) error {
+			owners[filepath.ToSlash(name)] = owner{uid, gid}
+			return nil
+		}))
+	})
+
+	Context("with a tar archive", func() {
+		BeforeEach(func() {
+			test_helper.CreateTarArchive(extractionSrc, archiveFiles)
+		})
+
+		It("leaves ownership alone by default", func() {
+			err := NewTar().Extract(extractionSrc, extractionDest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(owners).To(BeEmpty())
+		})
+
+		Context("WithOwnership", func() {
+			It("restores the archived owners", func() {
+				err := NewTar(WithOwnership()).Extract(extractionSrc, extractionDest)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(owners).To(Equal(map[string]owner{
+					"some-dir":           {0, 0},
+					"some-dir/some-file": {1000, 1001},
+					"some-symlink":       {1000, 1001},
+					"nobody-file":        {70000, 70000},
+				}))
+			})
+		})
+
+		Context("WithUIDMap and WithGIDMap", func() {
+			It("maps the archived owners to the host", func() {
+				mapping := idmap.Mapping{{ContainerID: 0, HostID: 100000, Size: 65536}}
+
+				err := NewTar(WithUIDMap(mapping), WithGIDMap(mapping)).Extract(extractionSrc, extractionDest)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(owners).To(Equal(map[string]owner{
+					"some-dir":           {100000, 100000},
+					"some-dir/some-file": {101000, 101001},
+					"some-symlink":       {101000, 101001},
+					"nobody-file":        {idmap.OverflowID, idmap.OverflowID},
+				}))
+			})
+		})
+
+		Context("WithOwner", func() {
+			It("gives every entry the same owner", func() {
+				err := NewTar(WithOwner(4000, 4001)).Extract(extractionSrc, extractionDest)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(owners).To(Equal(map[string]owner{
+					"some-dir":           {4000, 4001},
+					"some-dir/some-file": {4000, 4001},
+					"some-symlink":       {4000, 4001},
+					"nobody-file":        {4000, 4001},
+				}))
+			})
+		})
+	})
+
+	Context("with a zip archive", func() {
+		BeforeEach(func() {
+			test_helper.CreateZipArchive(extractionSrc, []test_helper.ArchiveFile{
+				{Name: "some-dir/", Dir: true},
+				{Name: "some-dir/some-file", Body: "some-file-contents"},
+			})
+		})
+
+		It("has no owners to restore", func() {
+			err := NewZip(WithOwnership()).Extract(extractionSrc, extractionDest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(owners).To(BeEmpty())
+		})
+
+		It("can still give every entry the same owner", func() {
+			err := NewZip(WithOwner(4000, 4001)).Extract(extractionSrc, extractionDest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(owners).To(Equal(map[string]owner{
+				"some-dir":           {4000, 4001},
+				"some-dir/some-file": {4000, 4001},
+			}))
+		})
+	})
+})
@@ -0,0 +1,180 @@
+//go:build unix
+
+package extractor_test
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "code.cloudfoundry.org/archiver/extractor"
+	"code.cloudfoundry.org/archiver/extractor/test_helper"
+)
+
+var _ = Describe("Permissions", func() {
+	var extractionDest string
+	var extractionSrc string
+
+	BeforeEach(func() {
+		extractionDest = tempDest()
+		extractionSrc = tempArchive()
+
+		oldUmask := syscall.Umask(0077)
+		DeferCleanup(func() {
+			syscall.Umask(oldUmask)
+		})
+		DeferCleanup(RereadUmask())
+
+		test_helper.CreateTarArchive(extractionSrc, []test_helper.ArchiveFile{
+			{
+				Name: "open-dir/",
+				Dir:  true,
+				Mode: 0777,
+			},
+			{
+				Name: "sticky-dir/",
+				Dir:  true,
+				Mode: 01777,
+			},
+			{
+				Name: "open-file",
+				Body: "open-file-contents",
+				Mode: 0777,
+			},
+			{
+				Name: "setuid-file",
+				Body: "setuid-file-contents",
+				Mode: 04755,
+			},
+			{
+				Name: "setgid-file",
+				Body: "setgid-file-contents",
+				Mode: 02755,
+			},
+		})
+	})
+
+	modeOf := func(name string) os.FileMode {
+		info, err := os.Stat(filepath.Join(extractionDest, name))
+		Expect(err).NotTo(HaveOccurred())
+		return info.Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+	}
+
+	It("masks archived modes with the process umask by default", func() {
+		err := NewTar().Extract(extractionSrc, extractionDest)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(modeOf("open-dir")).To(Equal(os.FileMode(0700)))
+		Expect(modeOf("sticky-dir")).To(Equal(os.ModeSticky | 0700))
+		Expect(modeOf("open-file")).To(Equal(os.FileMode(0700)))
+	})
+
+	It("leaves the modes of directories that already existed alone", func() {
+		test_helper.CreateTarArchive(extractionSrc, []test_helper.ArchiveFile{
+			{Name: "./", Dir: true, Mode: 0700},
+			{Name: "./existing-dir/", Dir: true, Mode: 0700},
+			{Name: "./existing-dir/some-file", Body: "some-file-contents"},
+		})
+		Expect(os.Mkdir(filepath.Join(extractionDest, "existing-dir"), 0755)).To(Succeed())
+		Expect(os.Chmod(filepath.Join(extractionDest, "existing-dir"), 0755)).To(Succeed())
+		Expect(os.Chmod(extractionDest, 0755)).To(Succeed())
+
+		err := NewTar().Extract(extractionSrc, extractionDest)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(modeOf(".")).To(Equal(os.FileMode(0755)))
+		Expect(modeOf("existing-dir")).To(Equal(os.FileMode(0755)))
+	})
+
+	It("drops setuid and setgid bits by default", func() {
+		err := NewTar(WithExactPermissions()).Extract(extractionSrc, extractionDest)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(modeOf("setuid-file")).To(Equal(os.FileMode(0755)))
+		Expect(modeOf("setgid-file")).To(Equal(os.FileMode(0755)))
+	})
+
+	Context("WithSetuidSetgid", func() {
+		It("keeps setuid and setgid bits", func() {
+			err := NewTar(WithExactPermissions(), WithSetuidSetgid()).Extract(extractionSrc, extractionDest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(modeOf("setuid-file")).To(Equal(os.ModeSetuid | 0755))
+			Expect(modeOf("setgid-file")).To(Equal(os.ModeSetgid | 0755))
+		})
+	})
+
+	Context("when /proc is not mounted", func() {
+		BeforeEach(func() {
+			DeferCleanup(SetProcSelfStatus(filepath.Join(extractionDest, "missing-status")))
+		})
+
+		It("masks archived modes with the umask the process started with", func() {
+			syscall.Umask(0)
+
+			err := NewTar().Extract(extractionSrc, extractionDest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(modeOf("open-dir")).To(Equal(os.FileMode(0700)))
+			Expect(modeOf("open-file")).To(Equal(os.FileMode(0700)))
+		})
+	})
+
+	Context("WithExactPermissions", func() {
+		It("applies archived modes exactly, whatever the umask", func() {
+			err := NewTar(WithExactPermissions()).Extract(extractionSrc, extractionDest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(modeOf("open-dir")).To(Equal(os.FileMode(0777)))
+			Expect(modeOf("sticky-dir")).To(Equal(os.ModeSticky | 0777))
+			Expect(modeOf("open-file")).To(Equal(os.FileMode(0777)))
+		})
+
+		It("still masks zip entries that record no unix mode", func() {
+			file, err := os.Create(extractionSrc)
+			Expect(err).NotTo(HaveOccurred())
+
+			w := zip.NewWriter(file)
+			_, err = w.CreateHeader(&zip.FileHeader{Name: "some-dir/"})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = w.CreateHeader(&zip.FileHeader{Name: "some-dir/some-file", Method: zip.Deflate})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(w.Close()).To(Succeed())
+			Expect(file.Close()).To(Succeed())
+
+			err = NewZip(WithExactPermissions()).Extract(extractionSrc, extractionDest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(modeOf("some-dir")).To(Equal(os.FileMode(0700)))
+			Expect(modeOf("some-dir/some-file")).To(Equal(os.FileMode(0600)))
+		})
+	})
+
+	Context("WithUmask", func() {
+		It("clears the masked bits from files and directories", func() {
+			err := NewTar(WithUmask(0027)).Extract(extractionSrc, extractionDest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(modeOf("open-dir")).To(Equal(os.FileMode(0750)))
+			Expect(modeOf("sticky-dir")).To(Equal(os.ModeSticky | 0750))
+			Expect(modeOf("open-file")).To(Equal(os.FileMode(0750)))
+			Expect(modeOf("setuid-file")).To(Equal(os.FileMode(0750)))
+		})
+	})
+
+	Context("WithFixedPermissions", func() {
+		It("ignores the archived modes", func() {
+			err := NewTar(WithFixedPermissions(0640, 0750)).Extract(extractionSrc, extractionDest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(modeOf("open-dir")).To(Equal(os.FileMode(0750)))
+			Expect(modeOf("sticky-dir")).To(Equal(os.FileMode(0750)))
+			Expect(modeOf("open-file")).To(Equal(os.FileMode(0640)))
+			Expect(modeOf("setuid-file")).To(Equal(os.FileMode(0640)))
+		})
+	})
+})
@@ -0,0 +1,117 @@
+package extractor
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// A Matcher reports whether header, the first 512 bytes of an archive (or
+// all of it, if it is shorter), is in its format.
+type Matcher func(header []byte) bool
+
+type Factory func(opts ...Option) Extractor
+
+var ErrUnsupportedFormat = errors.New("unsupported archive type")
+
+type UnsupportedFormatError struct {
+	Src       string
+	Signature string
+}
+
+func (e *UnsupportedFormatError) Error() string {
+	if e.Src == "" {
+		return fmt.Sprintf("%s: %s", ErrUnsupportedFormat, e.Signature)
+	}
+
+	return fmt.Sprintf("%s is an %s: %s", e.Src, ErrUnsupportedFormat, e.Signature)
+}
+
+func (e *UnsupportedFormatError) Is(target error) bool {
+	return target == ErrUnsupportedFormat
+}
+
+// DefaultPriority is the priority of formats registered with Register,
+// including the built-in ones.
+const DefaultPriority = 0
+
+type format struct {
+	name     string
+	priority int
+	matcher  Matcher
+	factory  Factory
+}
+
+var registry struct {
+	sync.RWMutex
+	formats []format
+}
+
+func init() {
+	Register("zip", isZip, builtin(NewZip))
+	Register("tgz", isGzip, builtin(NewTgz))
+	Register("tar", isTar, builtin(NewTar))
+}
+
+func builtin(newExtractor func(...Option) ArchiveExtractor) Factory {
+	return func(opts ...Option) Extractor {
+		return newExtractor(opts...)
+	}
+}
+
+// Register makes a format available to NewDetectable at DefaultPriority,
+// after the built-in formats.
+func Register(name string, matcher Matcher, factory Factory) {
+	RegisterPriority(name, DefaultPriority, matcher, factory)
+}
+
+// RegisterPriority makes a format available to NewDetectable. Formats are
+// matched highest priority first, and in the order they were registered
+// within a priority, so a format above DefaultPriority is tried before the
+// built-in ones. Registering an existing name replaces it in place.
+func RegisterPriority(name string, priority int, matcher Matcher, factory Factory) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	f := format{name: name, priority: priority, matcher: matcher, factory: factory}
+
+	i := slices.IndexFunc(registry.formats, func(f format) bool {
+		return f.name == name
+	})
+	if i >= 0 {
+		registry.formats[i] = f
+	} else {
+		registry.formats = append(registry.formats, f)
+	}
+
+	slices.SortStableFunc(registry.formats, func(a, b format) int {
+		return cmp.Compare(b.priority, a.priority)
+	})
+}
+
+func Unregister(name string) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	registry.formats = slices.DeleteFunc(registry.formats, func(f format) bool {
+		return f.name == name
+	})
+}
+
+// lookupFormat calls the matchers and factory without the registry held, so
+// that they can register formats themselves.
+func lookupFormat(src string, header []byte, opts []Option) (Extractor, error) {
+	registry.RLock()
+	formats := slices.Clone(registry.formats)
+	registry.RUnlock()
+
+	for _, f := range formats {
+		if f.matcher(header) {
+			return f.factory(opts...), nil
+		}
+	}
+
+	return nil, &UnsupportedFormatError{Src: src, Signature: signature(header)}
+}
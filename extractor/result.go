@@ -0,0 +1,81 @@
+package extractor
+
+import "time"
+
+// ExtractResult describes a successful extraction. Paths are relative to the
+// destination.
+type ExtractResult struct {
+	// Files are the regular files, hard links, FIFOs and devices extracted,
+	// each with its size.
+	Files []ExtractedEntry
+	// Dirs are the directories extraction created, including parents that
+	// have no entries of their own. Directories that already existed are
+	// left out.
+	Dirs []ExtractedEntry
+	// Symlinks are the symlinks extracted, each with the length of its
+	// target as its size.
+	Symlinks []ExtractedEntry
+
+	// Skipped are the entries left out under the symlink, unsupported entry
+	// or conflict policies.
+	Skipped []SkippedEntry
+
+	// PathRewrites are the entries whose names reached outside of the
+	// destination and were extracted inside it instead. From is always the
+	// same as Name in these.
+	PathRewrites []Rewrite
+	// SymlinkRewrites are the symlinks whose targets were rewritten under
+	// SymlinkRewrite.
+	SymlinkRewrites []Rewrite
+
+	// BytesWritten is the total size of the files written.
+	BytesWritten int64
+
+	// SyncDuration is the time spent flushing extracted files and
+	// directories to stable storage, under WithFsync.
+	SyncDuration time.Duration
+}
+
+// ExtractedEntry is an archive entry, by name, and where it was extracted.
+// Name is empty for a parent directory created without an entry.
+type ExtractedEntry struct {
+	Name string
+	Path string
+	Size int64
+}
+
+// SkipReason is why an archive entry was left out.
+type SkipReason int
+
+const (
+	// SkippedSymlink is a symlink left out under SymlinkSkip.
+	SkippedSymlink SkipReason = iota
+	// SkippedUnsupported is an entry of a type that could not be extracted.
+	SkippedUnsupported
+	// SkippedConflict is an entry whose path already existed and was kept
+	// under ConflictSkip or ConflictKeepNewer.
+	SkippedConflict
+)
+
+// SkippedEntry is an archive entry, by name, that was left out.
+type SkippedEntry struct {
+	Name   string
+	Reason SkipReason
+}
+
+// Rewrite is a path or symlink target, archived as From, that was changed
+// to To to keep the entry archived as Name inside the destination. For a
+// path, From is the entry's name, so only a symlink target differs from Name.
+type Rewrite struct {
+	Name string
+	From string
+	To   string
+}
+
+func (x *extraction) extracted(entries *[]ExtractedEntry, path string, size int64) {
+	*entries = append(*entries, ExtractedEntry{Name: x.entry, Path: path, Size: size})
+}
+
+func (x *extraction) skip(reason SkipReason) {
+	x.result.Skipped = append(x.result.Skipped, SkippedEntry{Name: x.entry, Reason: reason})
+}
@@ -0,0 +1,77 @@
+package extractor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"io"
+	"os"
+)
+
+const sniffLen = 512
+
+func sniffFile(src string) ([]byte, error) {
+	fd, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	return sniff(bufio.NewReader(fd))
+}
+
+// sniff returns the first block of br without consuming it, so br can be
+// handed on to the matching extractor.
+func sniff(br *bufio.Reader) ([]byte, error) {
+	header, err := br.Peek(sniffLen)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	return header, nil
+}
+
+// signature names the well-known magic number at the start of header, or
+// hex-encodes its first bytes when there is none.
+func signature(header []byte) string {
+	switch {
+	case isGzip(header):
+		return "gzip"
+	case isZip(header):
+		return "zip"
+	case isBzip2(header):
+		return "bzip2"
+	case isTar(header):
+		return "tar"
+	case len(header) == 0:
+		return "empty"
+	}
+
+	return hex.EncodeToString(header[:min(len(header), 8)])
+}
+
+func isGzip(header []byte) bool {
+	return bytes.HasPrefix(header, []byte{0x1f, 0x8b})
+}
+
+func isZip(header []byte) bool {
+	return bytes.HasPrefix(header, []byte("PK\x03\x04")) ||
+		bytes.HasPrefix(header, []byte("PK\x05\x06")) ||
+		bytes.HasPrefix(header, []byte("PK\x07\x08"))
+}
+
+func isBzip2(header []byte) bool {
+	return len(header) >= 4 && bytes.HasPrefix(header, []byte("BZh")) && header[3] >= '1' && header[3] <= '9'
+}
+
+func isTar(header []byte) bool {
+	if len(header) >= 265 {
+		magic := header[257:265]
+		if bytes.HasPrefix(magic, []byte("ustar\x00")) || bytes.Equal(magic, []byte("ustar  \x00")) {
+			return true
+		}
+	}
+
+	// a lone end-of-archive block
+	return len(header) == sniffLen && bytes.Count(header, []byte{0}) == sniffLen
+}
@@ -0,0 +1,28 @@
+//go:build unix
+
+package extractor
+
+import (
+	"archive/tar"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// mknod creates the FIFO or device that hdr describes at path.
+func mknod(root *os.Root, path string, hdr *tar.Header) error {
+	mode := uint32(hdr.Mode & 0777)
+	switch hdr.Typeflag {
+	case tar.TypeFifo:
+		mode |= unix.S_IFIFO
+	case tar.TypeChar:
+		mode |= unix.S_IFCHR
+	case tar.TypeBlock:
+		mode |= unix.S_IFBLK
+	}
+
+	dev := unix.Mkdev(uint32(hdr.Devmajor), uint32(hdr.Devminor))
+	return atParent(root, path, func(dirfd int, name string) error {
+		return mknodat(dirfd, name, mode, dev)
+	})
+}
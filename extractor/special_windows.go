@@ -0,0 +1,13 @@
+//go:build windows
+
+package extractor
+
+import (
+	"archive/tar"
+	"errors"
+	"os"
+)
+
+func mknod(_ *os.Root, _ string, _ *tar.Header) error {
+	return errors.ErrUnsupported
+}
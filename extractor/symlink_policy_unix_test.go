@@ -0,0 +1,175 @@
+//go:build unix
+
+package extractor_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "code.cloudfoundry.org/archiver/extractor"
+	"code.cloudfoundry.org/archiver/extractor/test_helper"
+)
+
+var _ = Describe("Symlink policy", func() {
+	var extractionDest string
+	var extractionSrc string
+
+	archiveFiles := []test_helper.ArchiveFile{
+		{Name: "some-dir/", Dir: true},
+		{Name: "some-dir/some-file", Body: "some-file-contents"},
+		{Name: "inside-link", Link: "some-dir/some-file"},
+		{Name: "some-dir/up-link", Link: "../inside-link"},
+		{Name: "escaping-link", Link: "../../etc/passwd"},
+		{Name: "some-dir/absolute-link", Link: "/etc/passwd"},
+	}
+
+	BeforeEach(func() {
+		extractionDest = tempDest()
+		extractionSrc = tempArchive()
+	})
+
+	readlink := func(name string) string {
+		target, err := os.Readlink(filepath.Join(extractionDest, name))
+		Expect(err).NotTo(HaveOccurred())
+		return target
+	}
+
+	Context("with a tar archive", func() {
+		BeforeEach(func() {
+			test_helper.CreateTarArchive(extractionSrc, archiveFiles)
+		})
+
+		It("creates every symlink as archived by default", func() {
+			err := NewTar().Extract(extractionSrc, extractionDest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(readlink("escaping-link")).To(Equal("../../etc/passwd"))
+			Expect(readlink("some-dir/absolute-link")).To(Equal("/etc/passwd"))
+		})
+
+		Context("with SymlinkReject", func() {
+			DescribeTable("rejects symlinks pointing outside of the destination",
+				func(name, target string) {
+					test_helper.CreateTarArchive(extractionSrc, []test_helper.ArchiveFile{
+						{Name: "some-dir/", Dir: true},
+						{Name: name, Link: target},
+					})
+
+					err := NewTar(WithSymlinkPolicy(SymlinkReject)).Extract(extractionSrc, extractionDest)
+
+					var symlinkErr *SymlinkError
+					Expect(errors.As(err, &symlinkErr)).To(BeTrue(), "expected a *SymlinkError, got %v", err)
+					Expect(symlinkErr.Name).To(Equal(name))
+					Expect(symlinkErr.Target).To(Equal(target))
+				},
+				Entry("an escaping target", "escaping-link", "../../etc/passwd"),
+				Entry("an escaping target from a directory", "some-dir/escaping-link", "../.."),
+				Entry("an absolute target", "some-dir/absolute-link", "/etc/passwd"),
+			)
+
+			DescribeTable("rejects chains of symlinks that lead outside of the destination",
+				func(files []test_helper.ArchiveFile) {
+					test_helper.CreateTarArchive(extractionSrc, files)
+
+					err := NewTar(WithSymlinkPolicy(SymlinkReject)).Extract(extractionSrc, extractionDest)
+
+					var symlinkErr *SymlinkError
+					Expect(errors.As(err, &symlinkErr)).To(BeTrue(), "expected a *SymlinkError, got %v", err)
+					Expect(symlinkErr.Name).To(Equal("l2"))
+					Expect(symlinkErr.Target).To(Equal("a/l1/.."))
+				},
+				Entry("extracted in order", []test_helper.ArchiveFile{
+					{Name: "a/", Dir: true},
+					{Name: "a/l1", Link: ".."},
+					{Name: "l2", Link: "a/l1/.."},
+				}),
+				Entry("with the link they go through extracted last", []test_helper.ArchiveFile{
+					{Name: "a/", Dir: true},
+					{Name: "l2", Link: "a/l1/.."},
+					{Name: "a/l1", Link: ".."},
+				}),
+			)
+
+			It("allows symlinks that stay inside the destination", func() {
+				test_helper.CreateTarArchive(extractionSrc, archiveFiles[:4])
+
+				err := NewTar(WithSymlinkPolicy(SymlinkReject)).Extract(extractionSrc, extractionDest)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(readlink("inside-link")).To(Equal("some-dir/some-file"))
+				Expect(readlink("some-dir/up-link")).To(Equal("../inside-link"))
+			})
+		})
+
+		Context("with SymlinkRewrite", func() {
+			It("rewrites targets outside of the destination to relative ones inside it", func() {
+				err := NewTar(WithSymlinkPolicy(SymlinkRewrite)).Extract(extractionSrc, extractionDest)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(readlink("inside-link")).To(Equal("some-dir/some-file"))
+				Expect(readlink("some-dir/up-link")).To(Equal("../inside-link"))
+				Expect(readlink("escaping-link")).To(Equal("etc/passwd"))
+				Expect(readlink("some-dir/absolute-link")).To(Equal("../etc/passwd"))
+			})
+
+			It("rewrites chains of symlinks that lead outside of the destination", func() {
+				test_helper.CreateTarArchive(extractionSrc, []test_helper.ArchiveFile{
+					{Name: "a/", Dir: true},
+					{Name: "a/l1", Link: ".."},
+					{Name: "l2", Link: "a/l1/.."},
+				})
+
+				err := NewTar(WithSymlinkPolicy(SymlinkRewrite)).Extract(extractionSrc, extractionDest)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(readlink("a/l1")).To(Equal(".."))
+				Expect(readlink("l2")).To(Equal("."))
+
+				resolved, err := filepath.EvalSymlinks(filepath.Join(extractionDest, "l2"))
+				Expect(err).NotTo(HaveOccurred())
+				expected, err := filepath.EvalSymlinks(extractionDest)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resolved).To(Equal(expected))
+			})
+		})
+
+		Context("with SymlinkSkip", func() {
+			It("leaves out every symlink", func() {
+				err := NewTar(WithSymlinkPolicy(SymlinkSkip)).Extract(extractionSrc, extractionDest)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(filepath.Join(extractionDest, "some-dir", "some-file")).To(BeAnExistingFile())
+				for _, name := range []string{"inside-link", "some-dir/up-link", "escaping-link", "some-dir/absolute-link"} {
+					_, err := os.Lstat(filepath.Join(extractionDest, name))
+					Expect(os.IsNotExist(err)).To(BeTrue(), "expected %s not to exist", name)
+				}
+			})
+		})
+	})
+
+	Context("with a zip archive", func() {
+		It("applies the policy to zip symlinks", func() {
+			test_helper.CreateZipArchive(extractionSrc, archiveFiles)
+
+			err := NewZip(WithSymlinkPolicy(SymlinkRewrite)).Extract(extractionSrc, extractionDest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(readlink("escaping-link")).To(Equal("etc/passwd"))
+			Expect(readlink("some-dir/absolute-link")).To(Equal("../etc/passwd"))
+		})
+
+		It("refuses symlink entries with huge bodies", func() {
+			test_helper.CreateZipArchive(extractionSrc, []test_helper.ArchiveFile{
+				{Name: "huge-link", Link: strings.Repeat("a", 1<<20)},
+			})
+
+			err := NewZip().Extract(extractionSrc, extractionDest)
+			Expect(err).To(MatchError(ContainSubstring("huge-link has a target longer than")))
+		})
+	})
+})
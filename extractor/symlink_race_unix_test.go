@@ -0,0 +1,99 @@
+//go:build unix
+
+package extractor_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "code.cloudfoundry.org/archiver/extractor"
+	"code.cloudfoundry.org/archiver/extractor/test_helper"
+)
+
+var _ = Describe("Symlinks into the destination", func() {
+	var extractionDest string
+	var extractionSrc string
+	var outside string
+
+	BeforeEach(func() {
+		extractionDest = tempDest()
+		outside = tempDest()
+		extractionSrc = tempArchive()
+	})
+
+	Context("when an archived symlink points outside of the destination", func() {
+		BeforeEach(func() {
+			test_helper.CreateTarArchive(extractionSrc, []test_helper.ArchiveFile{
+				{
+					Name: "escape",
+					Link: outside,
+				},
+				{
+					Name: "escape/some-file",
+					Body: "some-file-contents",
+				},
+			})
+		})
+
+		It("does not write through it", func() {
+			err := NewTar().Extract(extractionSrc, extractionDest)
+			Expect(err).To(HaveOccurred())
+
+			Expect(filepath.Join(outside, "some-file")).NotTo(BeAnExistingFile())
+		})
+	})
+
+	Context("when another process swaps a directory for a symlink", func() {
+		BeforeEach(func() {
+			files := []test_helper.ArchiveFile{{Name: "racy-dir/", Dir: true}}
+			for i := range 100 {
+				files = append(files, test_helper.ArchiveFile{
+					Name: fmt.Sprintf("racy-dir/file-%d", i),
+					Body: "racy-file-contents",
+				})
+			}
+
+			test_helper.CreateTarArchive(extractionSrc, files)
+		})
+
+		It("never writes outside of the destination", func() {
+			racyDir := filepath.Join(extractionDest, "racy-dir")
+			movedDir := filepath.Join(extractionDest, "moved-dir")
+
+			stop := make(chan struct{})
+			var wg sync.WaitGroup
+			wg.Go(func() {
+				for {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+
+					if os.Rename(racyDir, movedDir) == nil {
+						os.Symlink(outside, racyDir)
+						os.Remove(racyDir)
+						os.Rename(movedDir, racyDir)
+					}
+				}
+			})
+
+			for range 50 {
+				// errors are expected whenever the swap is caught
+				NewTar().Extract(extractionSrc, extractionDest)
+			}
+
+			close(stop)
+			wg.Wait()
+
+			entries, err := os.ReadDir(outside)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(BeEmpty())
+		})
+	})
+})
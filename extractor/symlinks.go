@@ -0,0 +1,200 @@
+package extractor
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// maxSymlinkTarget caps the body read for a zip symlink entry, matching the
+// usual PATH_MAX.
+const maxSymlinkTarget = 4096
+
+// maxSymlinkHops caps the symlinks followed resolving one target, as Linux
+// does before failing with ELOOP.
+const maxSymlinkHops = 40
+
+// SymlinkPolicy decides what happens to symlinks whose targets are absolute
+// or climb out of the destination. Targets are resolved through the symlinks
+// already extracted, so that a chain of them cannot lead out either; os.Root
+// keeps extraction itself from following any symlink out.
+type SymlinkPolicy int
+
+const (
+	// SymlinkAllow creates every symlink with its archived target.
+	SymlinkAllow SymlinkPolicy = iota
+	// SymlinkReject fails extraction with a *SymlinkError.
+	SymlinkReject
+	// SymlinkRewrite makes the target relative and clamps it inside the
+	// destination, treating absolute targets as rooted there. A target that
+	// only leads out through a symlink extracted after it fails extraction
+	// with a *SymlinkError instead.
+	SymlinkRewrite
+	// SymlinkSkip leaves out every symlink, whatever its target.
+	SymlinkSkip
+)
+
+// SymlinkError reports a symlink whose target points outside of the
+// destination, which extraction rejects under SymlinkReject.
+type SymlinkError struct {
+	Name   string
+	Target string
+}
+
+func (e *SymlinkError) Error() string {
+	return fmt.Sprintf("symlink %q points outside of the destination: %s", e.Name, e.Target)
+}
+
+// symlink creates a symlink at path, an entry archived as name, under the
+// extraction's symlink policy.
+func (x *extraction) symlink(name, target, path string) error {
+	rewritten, err := x.symlinkTarget(name, target, path)
+	if err != nil {
+		return err
+	}
+
+	err = x.root.Symlink(rewritten, path)
+	if err != nil {
+		return err
+	}
+
+	if rewritten != target {
+		x.result.SymlinkRewrites = append(x.result.SymlinkRewrites, Rewrite{Name: name, From: target, To: rewritten})
+	}
+	x.extracted(&x.result.Symlinks, path, int64(len(rewritten)))
+
+	if x.opts.symlinks != SymlinkAllow {
+		x.links = append(x.links, extractedLink{name: name, path: path, target: rewritten})
+	}
+	return nil
+}
+
+func (x *extraction) symlinkTarget(name, target, linkPath string) (string, error) {
+	if x.opts.symlinks == SymlinkAllow {
+		return target, nil
+	}
+
+	dir := path.Dir(filepath.ToSlash(linkPath))
+	resolved, escaped, err := x.resolve(dir, target)
+	if err != nil {
+		return "", &SymlinkError{Name: name, Target: target}
+	}
+
+	if !escaped {
+		return target, nil
+	}
+
+	if x.opts.symlinks == SymlinkReject {
+		return "", &SymlinkError{Name: name, Target: target}
+	}
+
+	rewritten, err := filepath.Rel(filepath.FromSlash(path.Join("/", dir)), filepath.FromSlash(path.Join("/", resolved)))
+	if err != nil {
+		return "", err
+	}
+
+	return rewritten, nil
+}
+
+// resolve follows target from dir, both within the root, through whatever
+// symlinks are already there, and returns where it leads, clamped inside the
+// root, and whether it had to be clamped. Components that do not exist yet
+// are taken by name.
+func (x *extraction) resolve(dir, target string) (string, bool, error) {
+	var resolved []string
+	for _, part := range strings.Split(dir, "/") {
+		if part != "." && part != "" {
+			resolved = append(resolved, part)
+		}
+	}
+
+	escaped := false
+	pending := strings.Split(filepath.ToSlash(target), "/")
+	if path.IsAbs(filepath.ToSlash(target)) || filepath.IsAbs(target) {
+		escaped = true
+		resolved = nil
+	}
+
+	for hops := 0; len(pending) > 0; {
+		part := pending[0]
+		pending = pending[1:]
+
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			if len(resolved) == 0 {
+				escaped = true
+			} else {
+				resolved = resolved[:len(resolved)-1]
+			}
+			continue
+		}
+
+		resolved = append(resolved, part)
+		current := filepath.FromSlash(path.Join(resolved...))
+
+		info, err := x.root.Lstat(current)
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+
+		hops++
+		if hops > maxSymlinkHops {
+			return "", false, syscall.ELOOP
+		}
+
+		link, err := x.root.Readlink(current)
+		if err != nil {
+			return "", false, err
+		}
+
+		resolved = resolved[:len(resolved)-1]
+		if path.IsAbs(filepath.ToSlash(link)) || filepath.IsAbs(link) {
+			escaped = true
+			resolved = nil
+		}
+		pending = append(strings.Split(filepath.ToSlash(link), "/"), pending...)
+	}
+
+	return path.Join(resolved...), escaped, nil
+}
+
+// extractedLink is a symlink created under SymlinkReject or SymlinkRewrite,
+// for checkLinks to resolve again once every entry is in place.
+type extractedLink struct {
+	name   string
+	path   string
+	target string
+}
+
+// checkLinks resolves every symlink again, since one extracted later can
+// make an earlier target that stayed inside lead out through it.
+func (x *extraction) checkLinks() error {
+	for _, link := range x.links {
+		_, escaped, err := x.resolve(path.Dir(filepath.ToSlash(link.path)), link.target)
+		if err != nil || escaped {
+			return &SymlinkError{Name: link.name, Target: link.target}
+		}
+	}
+
+	return nil
+}
+
+// readSymlinkBody reads the target stored as a zip symlink entry's contents.
+func readSymlinkBody(name string, input io.Reader) (string, error) {
+	target, err := io.ReadAll(io.LimitReader(input, maxSymlinkTarget+1))
+	if err != nil {
+		return "", err
+	}
+
+	if len(target) > maxSymlinkTarget {
+		return "", fmt.Errorf("symlink %s has a target longer than %d bytes", name, maxSymlinkTarget)
+	}
+
+	return string(target), nil
+}
@@ -0,0 +1,9 @@
+//go:build unix
+
+package extractor
+
+import "os"
+
+func syncDir(d *os.File) error {
+	return d.Sync()
+}
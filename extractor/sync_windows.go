@@ -0,0 +1,10 @@
+//go:build windows
+
+package extractor
+
+import "os"
+
+// syncDir does nothing, since Windows cannot flush a directory handle.
+func syncDir(_ *os.File) error {
+	return nil
+}
@@ -2,22 +2,48 @@ package extractor
 
 import (
 	"archive/tar"
+	"context"
+	"io"
 	"os"
 )
 
-type tarExtractor struct{}
+type tarExtractor struct {
+	opts []Option
+}
 
-func NewTar() Extractor {
-	return &tarExtractor{}
+func NewTar(opts ...Option) ArchiveExtractor {
+	return &tarExtractor{opts: opts}
 }
 
 func (e *tarExtractor) Extract(src, dest string) error {
+	return e.ExtractContext(context.Background(), src, dest)
+}
+
+func (e *tarExtractor) ExtractContext(ctx context.Context, src, dest string) error {
+	_, err := e.ExtractWithResult(ctx, src, dest)
+	return err
+}
+
+func (e *tarExtractor) ExtractWithResult(ctx context.Context, src, dest string) (ExtractResult, error) {
 	fd, err := os.Open(src)
 	if err != nil {
-		return err
+		return ExtractResult{}, err
 	}
 	defer fd.Close()
 
-	tarReader := tar.NewReader(fd)
-	return extractTarArchive(tarReader, dest)
+	return e.ExtractReaderWithResult(ctx, fd, dest)
+}
+
+func (e *tarExtractor) ExtractReader(r io.Reader, dest string) error {
+	return e.ExtractReaderContext(context.Background(), r, dest)
+}
+
+func (e *tarExtractor) ExtractReaderContext(ctx context.Context, r io.Reader, dest string) error {
+	_, err := e.ExtractReaderWithResult(ctx, r, dest)
+	return err
+}
+
+func (e *tarExtractor) ExtractReaderWithResult(ctx context.Context, r io.Reader, dest string) (ExtractResult, error) {
+	archive := &countingReader{r: r}
+	return extractTarArchive(ctx, tar.NewReader(archive), archive, dest, newOptions(e.opts))
 }
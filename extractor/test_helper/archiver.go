@@ -7,17 +7,22 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"time"
 
 	. "github.com/onsi/gomega"
 )
 
 type ArchiveFile struct {
-	Name   string
-	Body   string
-	Mode   int64
-	Dir    bool
-	Link   string
-	Xattrs map[string]string
+	Name     string
+	Body     string
+	Mode     int64
+	Dir      bool
+	Link     string
+	HardLink string
+	ModTime  time.Time
+	Xattrs   map[string]string
+	Uid      int
+	Gid      int
 }
 
 func CreateZipArchive(filename string, files []ArchiveFile) {
@@ -28,7 +33,9 @@ func CreateZipArchive(filename string, files []ArchiveFile) {
 
 	for _, file := range files {
 		header := &zip.FileHeader{
-			Name: file.Name,
+			Name:     file.Name,
+			Method:   zip.Deflate,
+			Modified: file.ModTime,
 		}
 
 		mode := file.Mode
@@ -97,9 +104,14 @@ func WriteTar(destination io.Writer, files []ArchiveFile) {
 		}
 
 		if file.Dir {
+			dirMode := file.Mode
+			if dirMode == 0 {
+				dirMode = 0755
+			}
+
 			header = &tar.Header{
 				Name:     file.Name,
-				Mode:     0755,
+				Mode:     dirMode,
 				Typeflag: tar.TypeDir,
 			}
 		} else if file.Link != "" {
@@ -109,6 +121,13 @@ func WriteTar(destination io.Writer, files []ArchiveFile) {
 				Linkname: file.Link,
 				Mode:     file.Mode,
 			}
+		} else if file.HardLink != "" {
+			header = &tar.Header{
+				Name:     file.Name,
+				Typeflag: tar.TypeLink,
+				Linkname: file.HardLink,
+				Mode:     mode,
+			}
 		} else {
 			header = &tar.Header{
 				Name: file.Name,
@@ -117,6 +136,10 @@ func WriteTar(destination io.Writer, files []ArchiveFile) {
 			}
 		}
 
+		header.ModTime = file.ModTime
+		header.Uid = file.Uid
+		header.Gid = file.Gid
+
 		if header.PAXRecords == nil {
 			header.PAXRecords = map[string]string{}
 		}
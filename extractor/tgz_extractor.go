@@ -3,111 +3,238 @@ package extractor
 import (
 	"archive/tar"
 	"compress/gzip"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
-
-	securejoin "github.com/cyphar/filepath-securejoin"
+	"syscall"
 )
 
-type tgzExtractor struct{}
+type tgzExtractor struct {
+	opts []Option
+}
 
-func NewTgz() Extractor {
-	return &tgzExtractor{}
+func NewTgz(opts ...Option) ArchiveExtractor {
+	return &tgzExtractor{opts: opts}
 }
 
 func (e *tgzExtractor) Extract(src, dest string) error {
-	srcType, err := mimeType(src)
+	return e.ExtractContext(context.Background(), src, dest)
+}
+
+func (e *tgzExtractor) ExtractContext(ctx context.Context, src, dest string) error {
+	_, err := e.ExtractWithResult(ctx, src, dest)
+	return err
+}
+
+func (e *tgzExtractor) ExtractWithResult(ctx context.Context, src, dest string) (ExtractResult, error) {
+	header, err := sniffFile(src)
 	if err != nil {
-		return err
+		return ExtractResult{}, err
 	}
 
-	switch srcType {
-	case "application/x-gzip":
-		err := extractTgz(src, dest)
-		if err != nil {
-			return err
-		}
-	default:
-		return fmt.Errorf("%s is not a tgz archive: %s", src, srcType)
+	if !isGzip(header) {
+		return ExtractResult{}, fmt.Errorf("%s is not a tgz archive: %s", src, signature(header))
 	}
 
-	return nil
-}
-
-func extractTgz(src, dest string) error {
 	fd, err := os.Open(src)
 	if err != nil {
-		return err
+		return ExtractResult{}, err
 	}
 	defer fd.Close()
 
-	gReader, err := gzip.NewReader(fd)
+	return extractTgz(ctx, fd, dest, newOptions(e.opts))
+}
+
+func (e *tgzExtractor) ExtractReader(r io.Reader, dest string) error {
+	return e.ExtractReaderContext(context.Background(), r, dest)
+}
+
+func (e *tgzExtractor) ExtractReaderContext(ctx context.Context, r io.Reader, dest string) error {
+	_, err := e.ExtractReaderWithResult(ctx, r, dest)
+	return err
+}
+
+func (e *tgzExtractor) ExtractReaderWithResult(ctx context.Context, r io.Reader, dest string) (ExtractResult, error) {
+	return extractTgz(ctx, r, dest, newOptions(e.opts))
+}
+
+func extractTgz(ctx context.Context, r io.Reader, dest string, o *options) (ExtractResult, error) {
+	archive := &countingReader{r: r}
+	gReader, err := gzip.NewReader(archive)
 	if err != nil {
-		return err
+		return ExtractResult{}, err
 	}
 	defer gReader.Close()
 
-	tarReader := tar.NewReader(gReader)
-	return extractTarArchive(tarReader, dest)
+	return extractTarArchive(ctx, tar.NewReader(gReader), archive, dest, o)
 }
 
-func extractTarArchive(tarReader *tar.Reader, dest string) error {
+// extractTarArchive extracts the entries of tarReader, which reads from
+// archive, possibly through a decompressor.
+func extractTarArchive(ctx context.Context, tarReader *tar.Reader, archive *countingReader, dest string, o *options) (ExtractResult, error) {
+	x, err := newExtraction(ctx, dest, o)
+	if err != nil {
+		return ExtractResult{}, err
+	}
+	defer x.close()
+
+	x.archiveBytes = func() int64 { return archive.n }
+
 	for {
+		if err := ctx.Err(); err != nil {
+			return ExtractResult{}, x.abort(err)
+		}
+
 		hdr, err := tarReader.Next()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			return err
+			return ExtractResult{}, x.abort(err)
 		}
 
-		if hdr.Name == "." {
+		// a pax global header holds defaults for the entries after it, which
+		// the tar reader does not carry over; they are deliberately ignored
+		if hdr.Name == "." || hdr.Typeflag == tar.TypeXGlobalHeader {
 			continue
 		}
 
-		err = extractTarArchiveFile(hdr, dest, tarReader)
+		err = x.extractTarArchiveFile(hdr, tarReader)
 		if err != nil {
-			return err
+			return ExtractResult{}, x.abort(err)
 		}
 	}
 
-	return nil
+	return x.finish()
 }
 
-func extractTarArchiveFile(header *tar.Header, dest string, input io.Reader) error {
-	filePath, err := securejoin.SecureJoin(dest, header.Name)
+func (x *extraction) extractTarArchiveFile(header *tar.Header, input io.Reader) error {
+	filePath, err := x.entryPath(header.Name)
+	if err != nil {
+		return err
+	}
 
+	err = x.startEntry(header.Name, filePath)
 	if err != nil {
 		return err
 	}
 	fileInfo := header.FileInfo()
 
+	switch header.Typeflag {
+	case tar.TypeReg, tar.TypeCont, tar.TypeGNUSparse, tar.TypeDir, tar.TypeSymlink, tar.TypeLink, tar.TypeFifo:
+	case tar.TypeChar, tar.TypeBlock:
+		if !x.opts.devices {
+			return x.unsupported(header)
+		}
+	default:
+		return x.unsupported(header)
+	}
+
+	if fileInfo.Mode()&os.ModeSymlink != 0 && x.opts.symlinks == SymlinkSkip {
+		x.skip(SkippedSymlink)
+		return nil
+	}
+
+	extract, err := x.resolveConflict(header.Name, filePath, fileInfo.IsDir(), header.ModTime)
+	if err != nil || !extract {
+		return err
+	}
+
+	x.track(filePath)
+	x.touch(filePath)
+
+	var file *os.File
 	if fileInfo.IsDir() {
-		return os.MkdirAll(filePath, fileInfo.Mode())
+		err = x.mkdir(filePath, fileInfo.Mode(), header.AccessTime, header.ModTime)
+		if err != nil || !x.made[filePath] {
+			return err
+		}
+
+		err = x.chown(filePath, header.Uid, header.Gid)
+	} else {
+		err = x.mkdirAll(filepath.Dir(filePath))
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case fileInfo.Mode()&os.ModeSymlink != 0:
+			err = x.symlink(header.Name, header.Linkname, filePath)
+			if err == nil {
+				err = x.chown(filePath, header.Uid, header.Gid)
+			}
+		case header.Typeflag == tar.TypeLink:
+			err = x.extractHardLink(header, filePath)
+		case fileInfo.Mode()&(os.ModeNamedPipe|os.ModeDevice) != 0:
+			err = mknod(x.root, filePath, header)
+			if errors.Is(err, syscall.EPERM) || errors.Is(err, errors.ErrUnsupported) {
+				return x.unsupported(header)
+			}
+			if err == nil {
+				err = x.chown(filePath, header.Uid, header.Gid)
+			}
+			if err == nil {
+				err = x.root.Chmod(filePath, x.opts.permissions(fileInfo.Mode()))
+			}
+			if err == nil {
+				x.extracted(&x.result.Files, filePath, 0)
+			}
+		default:
+			file, err = x.writeFile(filePath, fileInfo.Mode(), header.Uid, header.Gid, input)
+			if err == nil {
+				defer file.Close()
+			}
+		}
 	}
 
-	err = os.MkdirAll(filepath.Dir(filePath), 0755)
 	if err != nil {
 		return err
 	}
 
-	if fileInfo.Mode()&os.ModeSymlink != 0 {
-		return os.Symlink(header.Linkname, filePath)
+	err = setXattrsFromTar(x.root, filePath, header, x.opts)
+	if err != nil {
+		return err
+	}
+
+	if header.Typeflag == tar.TypeLink || fileInfo.IsDir() {
+		return nil
+	}
+
+	err = x.setTimes(filePath, fileInfo.Mode(), header.AccessTime, header.ModTime)
+	if err != nil {
+		return err
+	}
+
+	return x.sync(file)
+}
+
+func (x *extraction) extractHardLink(header *tar.Header, filePath string) error {
+	if x.opts.strictPaths && escapes(header.Linkname) {
+		return &PathTraversalError{Name: header.Name}
+	}
+
+	if !filepath.IsLocal(filepath.FromSlash(header.Linkname)) {
+		return fmt.Errorf("hard link %s points outside of the destination: %s", header.Name, header.Linkname)
 	}
 
-	fileCopy, err := os.OpenFile(filePath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, fileInfo.Mode())
+	target, err := x.entryPath(header.Linkname)
 	if err != nil {
 		return err
 	}
 
-	defer fileCopy.Close()
+	err = x.root.Link(target, filePath)
+	if err != nil {
+		return err
+	}
 
-	_, err = io.Copy(fileCopy, input)
+	info, err := x.root.Lstat(filePath)
 	if err != nil {
 		return err
 	}
 
-	return setXattrsFromTar(filePath, header)
+	x.extracted(&x.result.Files, filePath, info.Size())
+	return nil
 }
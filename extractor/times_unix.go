@@ -0,0 +1,19 @@
+//go:build unix
+
+package extractor
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func lchtimes(root *os.Root, path string, atime, mtime time.Time) error {
+	return atParent(root, path, func(dirfd int, name string) error {
+		return unix.UtimesNanoAt(dirfd, name, []unix.Timespec{
+			unix.NsecToTimespec(atime.UnixNano()),
+			unix.NsecToTimespec(mtime.UnixNano()),
+		}, unix.AT_SYMLINK_NOFOLLOW)
+	})
+}
@@ -0,0 +1,12 @@
+//go:build windows
+
+package extractor
+
+import (
+	"os"
+	"time"
+)
+
+func lchtimes(_ *os.Root, _ string, _, _ time.Time) error {
+	return nil
+}
@@ -0,0 +1,67 @@
+//go:build unix
+
+package extractor
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+var procSelfStatus = "/proc/self/status"
+
+// initialUmask stands in for the umask when /proc cannot report it.
+var initialUmask os.FileMode
+
+func init() {
+	if _, ok := statusUmask(); !ok {
+		initialUmask = swapUmask()
+	}
+}
+
+// processUmask returns the umask of the process, as Linux reports it in
+// /proc/self/status, or else as it was at init.
+func processUmask() os.FileMode {
+	if mask, ok := statusUmask(); ok {
+		return mask
+	}
+
+	return initialUmask
+}
+
+// swapUmask reads the umask the only way there is without /proc, by setting
+// it and setting it back. Any goroutine creating files in between would get
+// the wrong umask, so this is only done at init, and a later change of umask
+// goes unseen.
+func swapUmask() os.FileMode {
+	mask := unix.Umask(0022)
+	unix.Umask(mask)
+	return os.FileMode(mask) & os.ModePerm
+}
+
+func statusUmask() (os.FileMode, bool) {
+	status, err := os.Open(procSelfStatus)
+	if err != nil {
+		return 0, false
+	}
+	defer status.Close()
+
+	scanner := bufio.NewScanner(status)
+	for scanner.Scan() {
+		value, found := strings.CutPrefix(scanner.Text(), "Umask:")
+		if !found {
+			continue
+		}
+
+		mask, err := strconv.ParseUint(strings.TrimSpace(value), 8, 32)
+		if err != nil {
+			return 0, false
+		}
+		return os.FileMode(mask) & os.ModePerm, true
+	}
+
+	return 0, false
+}
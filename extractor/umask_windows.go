@@ -0,0 +1,9 @@
+//go:build windows
+
+package extractor
+
+import "os"
+
+func processUmask() os.FileMode {
+	return 0
+}
@@ -0,0 +1,37 @@
+package extractor
+
+import (
+	"archive/tar"
+	"fmt"
+)
+
+// UnsupportedEntryPolicy decides what happens to archive entries that
+// cannot be extracted.
+type UnsupportedEntryPolicy int
+
+const (
+	// UnsupportedSkip leaves unsupported entries out.
+	UnsupportedSkip UnsupportedEntryPolicy = iota
+	// UnsupportedError fails extraction with an *UnsupportedEntryError.
+	UnsupportedError
+)
+
+// UnsupportedEntryError reports an archive entry of a type that could not be
+// extracted, which extraction rejects under UnsupportedError.
+type UnsupportedEntryError struct {
+	Name     string
+	Typeflag byte
+}
+
+func (e *UnsupportedEntryError) Error() string {
+	return fmt.Sprintf("archive entry %q has unsupported type %q", e.Name, e.Typeflag)
+}
+
+func (x *extraction) unsupported(header *tar.Header) error {
+	if x.opts.unsupported == UnsupportedError {
+		return &UnsupportedEntryError{Name: header.Name, Typeflag: header.Typeflag}
+	}
+
+	x.skip(SkippedUnsupported)
+	return nil
+}
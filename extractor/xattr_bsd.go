@@ -0,0 +1,11 @@
+//go:build darwin || freebsd || netbsd
+
+package extractor
+
+import "os"
+
+// lsetxattrAt sets the attribute through a descriptor for the entry itself,
+// since there is no call relative to a directory descriptor here.
+func lsetxattrAt(root *os.Root, path, name string, value []byte) error {
+	return fsetxattrAt(root, path, name, value)
+}
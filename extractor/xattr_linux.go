@@ -0,0 +1,31 @@
+//go:build linux
+
+package extractor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+var procSelfFd = "/proc/self/fd"
+
+// lsetxattrAt reaches the entry through its parent's descriptor under
+// /proc/self/fd, so that symlinks get attributes of their own. Without /proc
+// it falls back to a descriptor for the entry itself.
+func lsetxattrAt(root *os.Root, path, name string, value []byte) error {
+	err := atParent(root, path, func(dirfd int, base string) error {
+		return unix.Lsetxattr(fmt.Sprintf("%s/%d/%s", procSelfFd, dirfd, base), name, value, 0)
+	})
+	if !errors.Is(err, unix.ENOENT) {
+		return err
+	}
+
+	if _, statErr := os.Stat(procSelfFd); statErr == nil {
+		return err
+	}
+
+	return fsetxattrAt(root, path, name, value)
+}
@@ -0,0 +1,114 @@
+//go:build linux
+
+package extractor_test
+
+import (
+	"errors"
+	"path/filepath"
+	"syscall"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/sys/unix"
+
+	. "code.cloudfoundry.org/archiver/extractor"
+	"code.cloudfoundry.org/archiver/extractor/test_helper"
+)
+
+var _ = Describe("Extended attributes", func() {
+	var extractionDest string
+	var extractionSrc string
+
+	BeforeEach(func() {
+		extractionDest = tempDest()
+
+		err := unix.Lsetxattr(extractionDest, "user.probe", []byte("probe"), 0)
+		if errors.Is(err, syscall.ENOTSUP) {
+			Skip("the filesystem does not support user extended attributes")
+		}
+		Expect(err).NotTo(HaveOccurred())
+
+		extractionSrc = tempArchive()
+	})
+
+	getXattr := func(path, name string) (string, error) {
+		value := make([]byte, 64)
+		size, err := unix.Lgetxattr(path, name, value)
+		if err != nil {
+			return "", err
+		}
+		return string(value[:size]), nil
+	}
+
+	Context("with attributes on every entry type", func() {
+		BeforeEach(func() {
+			test_helper.CreateTarArchive(extractionSrc, []test_helper.ArchiveFile{
+				{
+					Name:   "some-dir/",
+					Dir:    true,
+					Xattrs: map[string]string{"user.dir": "dir-value", "trusted.dir": "trusted-value"},
+				},
+				{
+					Name:   "some-dir/some-file",
+					Body:   "some-file-contents",
+					Xattrs: map[string]string{"user.file": "file-value"},
+				},
+				{
+					Name:   "some-symlink",
+					Link:   "some-dir/some-file",
+					Xattrs: map[string]string{"user.link": "link-value"},
+				},
+			})
+		})
+
+		It("applies them to directories as well as files", func() {
+			Expect(NewTar().Extract(extractionSrc, extractionDest)).To(Succeed())
+
+			Expect(getXattr(filepath.Join(extractionDest, "some-dir"), "user.dir")).To(Equal("dir-value"))
+			Expect(getXattr(filepath.Join(extractionDest, "some-dir", "some-file"), "user.file")).To(Equal("file-value"))
+		})
+
+		It("applies only allowed namespaces", func() {
+			Expect(NewTar(WithXattrAllow("user")).Extract(extractionSrc, extractionDest)).To(Succeed())
+
+			Expect(getXattr(filepath.Join(extractionDest, "some-dir"), "user.dir")).To(Equal("dir-value"))
+			_, err := getXattr(filepath.Join(extractionDest, "some-dir"), "trusted.dir")
+			Expect(err).To(MatchError(unix.ENODATA))
+		})
+
+		It("skips attributes the symlink cannot carry", func() {
+			Expect(NewTar().Extract(extractionSrc, extractionDest)).To(Succeed())
+
+			Expect(filepath.Join(extractionDest, "some-symlink")).To(BeAnExistingFile())
+		})
+
+		Context("in strict mode", func() {
+			It("reports the attributes it could not apply", func() {
+				// user attributes are not permitted on symlinks
+				err := NewTar(WithStrictXattrs()).Extract(extractionSrc, extractionDest)
+				Expect(err).To(MatchError(syscall.EPERM))
+				Expect(err).To(MatchError(ContainSubstring("user.link")))
+			})
+		})
+
+		Context("when /proc is not mounted", func() {
+			var restore func()
+
+			BeforeEach(func() {
+				restore = SetProcSelfFd(filepath.Join(extractionDest, "no-proc"))
+			})
+
+			AfterEach(func() {
+				restore()
+			})
+
+			It("applies them to files and directories through their own descriptors", func() {
+				Expect(NewTar().Extract(extractionSrc, extractionDest)).To(Succeed())
+
+				Expect(getXattr(filepath.Join(extractionDest, "some-dir"), "user.dir")).To(Equal("dir-value"))
+				Expect(getXattr(filepath.Join(extractionDest, "some-dir", "some-file"), "user.file")).To(Equal("file-value"))
+				Expect(filepath.Join(extractionDest, "some-symlink")).To(BeAnExistingFile())
+			})
+		})
+	})
+})
@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !freebsd && !netbsd
+
+package extractor
+
+import (
+	"archive/tar"
+	"os"
+)
+
+func setXattrsFromTar(_ *os.Root, _ string, _ *tar.Header, _ *options) error {
+	return nil
+}
@@ -1,17 +1,19 @@
-//go:build unix
+//go:build linux || darwin || freebsd || netbsd
 
 package extractor
 
 import (
 	"archive/tar"
 	"errors"
+	"fmt"
+	"os"
 	"strings"
 	"syscall"
 
 	"golang.org/x/sys/unix"
 )
 
-func setXattrsFromTar(path string, hdr *tar.Header) (err error) {
+func setXattrsFromTar(root *os.Root, path string, hdr *tar.Header, o *options) (err error) {
 	const paxSchilyXattr = "SCHILY.xattr."
 
 	for key, value := range hdr.PAXRecords {
@@ -19,11 +21,43 @@ func setXattrsFromTar(path string, hdr *tar.Header) (err error) {
 			continue
 		}
 
-		err = unix.Lsetxattr(path, key[len(paxSchilyXattr):], []byte(value), 0)
-		if err != nil && !errors.Is(err, syscall.ENOTSUP) && !errors.Is(err, syscall.EPERM) {
-			return err
+		name := key[len(paxSchilyXattr):]
+		if !o.appliesXattr(name) {
+			continue
+		}
+
+		err = lsetxattrAt(root, path, name, []byte(value))
+		if err == nil {
+			continue
+		}
+
+		if o.strictXattrs || (!errors.Is(err, syscall.ENOTSUP) && !errors.Is(err, syscall.EPERM)) {
+			return fmt.Errorf("setting extended attribute %s on %s: %w", name, hdr.Name, err)
 		}
 	}
 
 	return nil
 }
+
+// fsetxattrAt sets the attribute through a descriptor for the entry itself.
+// Only files and directories are opened, since opening a symlink follows it
+// and opening a FIFO or device can block or act on the device, so anything
+// else is left without.
+func fsetxattrAt(root *os.Root, path, name string, value []byte) error {
+	info, err := root.Lstat(path)
+	if err != nil {
+		return err
+	}
+
+	if !info.Mode().IsRegular() && !info.IsDir() {
+		return unix.ENOTSUP
+	}
+
+	file, err := root.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return unix.Fsetxattr(int(file.Fd()), name, value, 0)
+}
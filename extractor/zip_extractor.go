@@ -2,103 +2,243 @@ package extractor
 
 import (
 	"archive/zip"
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
-
-	securejoin "github.com/cyphar/filepath-securejoin"
+	"time"
 )
 
-type zipExtractor struct{}
+type zipExtractor struct {
+	opts []Option
+}
 
-func NewZip() Extractor {
-	return &zipExtractor{}
+func NewZip(opts ...Option) ArchiveExtractor {
+	return &zipExtractor{opts: opts}
 }
 
 func (e *zipExtractor) Extract(src, dest string) error {
-	srcType, err := mimeType(src)
+	return e.ExtractContext(context.Background(), src, dest)
+}
+
+func (e *zipExtractor) ExtractContext(ctx context.Context, src, dest string) error {
+	_, err := e.ExtractWithResult(ctx, src, dest)
+	return err
+}
+
+func (e *zipExtractor) ExtractWithResult(ctx context.Context, src, dest string) (ExtractResult, error) {
+	header, err := sniffFile(src)
 	if err != nil {
-		return err
+		return ExtractResult{}, err
 	}
 
-	switch srcType {
-	case "application/zip":
-		err := extractZip(src, dest)
-		if err != nil {
-			return err
+	if !isZip(header) {
+		return ExtractResult{}, fmt.Errorf("%s is not a zip archive: %s", src, signature(header))
+	}
+
+	archive, err := os.Open(src)
+	if err != nil {
+		return ExtractResult{}, err
+	}
+	defer archive.Close()
+
+	info, err := archive.Stat()
+	if err != nil {
+		return ExtractResult{}, err
+	}
+
+	return extractZip(ctx, archive, info.Size(), dest, newOptions(e.opts))
+}
+
+func (e *zipExtractor) ExtractReader(r io.Reader, dest string) error {
+	return e.ExtractReaderContext(context.Background(), r, dest)
+}
+
+func (e *zipExtractor) ExtractReaderContext(ctx context.Context, r io.Reader, dest string) error {
+	_, err := e.ExtractReaderWithResult(ctx, r, dest)
+	return err
+}
+
+func (e *zipExtractor) ExtractReaderWithResult(ctx context.Context, r io.Reader, dest string) (ExtractResult, error) {
+	readerAt, size, cleanup, err := sizedReaderAt(ctx, r)
+	if err != nil {
+		return ExtractResult{}, err
+	}
+	defer cleanup()
+
+	return extractZip(ctx, readerAt, size, dest, newOptions(e.opts))
+}
+
+// sizedReaderAt gives zip the random access it needs, spooling r to a
+// temporary file when it is a plain stream.
+func sizedReaderAt(ctx context.Context, r io.Reader) (io.ReaderAt, int64, func(), error) {
+	if fd, ok := r.(*os.File); ok {
+		info, err := fd.Stat()
+		if err == nil && info.Mode().IsRegular() {
+			return fd, info.Size(), func() {}, nil
 		}
-	default:
-		return fmt.Errorf("%s is not a zip archive: %s", src, srcType)
 	}
 
-	return nil
+	if sized, ok := r.(interface {
+		io.ReaderAt
+		Size() int64
+	}); ok {
+		return sized, sized.Size(), func() {}, nil
+	}
+
+	tmp, err := os.CreateTemp("", "archiver-zip")
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	cleanup := func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}
+
+	size, err := io.Copy(tmp, &contextReader{ctx: ctx, r: r})
+	if err != nil {
+		cleanup()
+		return nil, 0, nil, err
+	}
+
+	return tmp, size, cleanup, nil
 }
 
-func extractZip(src, dest string) error {
-	files, err := zip.OpenReader(src)
+// extractZip counts the archive bytes actually read for MaxRatio, as the
+// compressed sizes in the central directory are the archive's to forge.
+func extractZip(ctx context.Context, r io.ReaderAt, size int64, dest string, o *options) (ExtractResult, error) {
+	archive := &countingReaderAt{r: r}
+
+	files, err := zip.NewReader(archive, size)
 	if err != nil {
-		return err
+		return ExtractResult{}, err
 	}
 
-	defer files.Close()
+	x, err := newExtraction(ctx, dest, o)
+	if err != nil {
+		return ExtractResult{}, err
+	}
+	defer x.close()
+
+	x.archiveBytes = func() int64 { return archive.n }
 
 	for _, file := range files.File {
-		err = func() error {
+		if err := ctx.Err(); err != nil {
+			return ExtractResult{}, x.abort(err)
+		}
+
+		err := func() error {
 			readCloser, err := file.Open()
 			if err != nil {
 				return err
 			}
 			defer readCloser.Close()
 
-			return extractZipArchiveFile(file, dest, readCloser)
+			return x.extractZipArchiveFile(file, readCloser)
 		}()
 
 		if err != nil {
-			return err
+			return ExtractResult{}, x.abort(err)
 		}
 	}
 
-	return nil
+	return x.finish()
 }
 
-func extractZipArchiveFile(file *zip.File, dest string, input io.Reader) error {
-	filePath, err := securejoin.SecureJoin(dest, file.Name)
+func (x *extraction) extractZipArchiveFile(file *zip.File, input io.Reader) error {
+	filePath, err := x.entryPath(file.Name)
+	if err != nil {
+		return err
+	}
+
+	err = x.startEntry(file.Name, filePath)
 	if err != nil {
 		return err
 	}
 	fileInfo := file.FileInfo()
+	mode := zipMode(file, x.opts)
+
+	if mode&os.ModeSymlink != 0 && x.opts.symlinks == SymlinkSkip {
+		x.skip(SkippedSymlink)
+		return nil
+	}
+
+	extract, err := x.resolveConflict(file.Name, filePath, fileInfo.IsDir(), fileInfo.ModTime())
+	if err != nil || !extract {
+		return err
+	}
+
+	x.track(filePath)
+	x.touch(filePath)
 
 	if fileInfo.IsDir() {
-		err = os.MkdirAll(filePath, fileInfo.Mode())
-		if err != nil {
+		err = x.mkdir(filePath, mode, time.Time{}, fileInfo.ModTime())
+		if err != nil || !x.made[filePath] {
 			return err
 		}
-	} else {
-		err = os.MkdirAll(filepath.Dir(filePath), 0755)
+
+		return x.chown(filePath, -1, -1)
+	}
+
+	err = x.mkdirAll(filepath.Dir(filePath))
+	if err != nil {
+		return err
+	}
+
+	var fileCopy *os.File
+	if mode&os.ModeSymlink != 0 {
+		linkName, err := readSymlinkBody(file.Name, input)
 		if err != nil {
 			return err
 		}
 
-		if fileInfo.Mode()&os.ModeSymlink != 0 {
-			linkName, err := io.ReadAll(input)
-			if err != nil {
-				return err
-			}
-			return os.Symlink(string(linkName), filePath)
+		err = x.symlink(file.Name, linkName, filePath)
+		if err != nil {
+			return err
 		}
 
-		fileCopy, err := os.OpenFile(filePath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, fileInfo.Mode())
+		err = x.chown(filePath, -1, -1)
 		if err != nil {
 			return err
 		}
-		defer fileCopy.Close()
-
-		_, err = io.Copy(fileCopy, input)
+	} else {
+		fileCopy, err = x.writeFile(filePath, mode, -1, -1, input)
 		if err != nil {
 			return err
 		}
+		defer fileCopy.Close()
+	}
+
+	err = x.setTimes(filePath, mode, time.Time{}, fileInfo.ModTime())
+	if err != nil {
+		return err
+	}
+
+	return x.sync(fileCopy)
+}
+
+// the zip creator systems whose entries record unix modes
+const (
+	creatorUnix   = 3
+	creatorMacOSX = 19
+)
+
+// zipMode returns the mode of a zip entry. Entries that record no unix mode,
+// as jar and Windows tools write them, get 0666 or, for directories, 0777,
+// with the umask applied even under WithExactPermissions.
+func zipMode(file *zip.File, o *options) os.FileMode {
+	mode := file.Mode()
+
+	creator := file.CreatorVersion >> 8
+	if (creator == creatorUnix || creator == creatorMacOSX) && file.ExternalAttrs>>16 != 0 {
+		return mode
+	}
+
+	if mode.IsDir() {
+		mode |= 0777
 	}
 
-	return nil
+	return mode &^ o.mask()
 }
@@ -3,7 +3,6 @@ module code.cloudfoundry.org/archiver
 go 1.25.0
 
 require (
-	github.com/cyphar/filepath-securejoin v0.7.0
 	github.com/onsi/ginkgo/v2 v2.32.0
 	github.com/onsi/gomega v1.42.1
 	golang.org/x/sys v0.47.0
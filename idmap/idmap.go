@@ -0,0 +1,84 @@
+package idmap
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// OverflowID is the ID that IDs without a mapping appear as, like the
+// kernel's overflowuid and overflowgid.
+const OverflowID = 65534
+
+// Range maps Size consecutive IDs starting at ContainerID inside a user
+// namespace to the IDs starting at HostID outside it.
+type Range struct {
+	ContainerID int
+	HostID      int
+	Size        int
+}
+
+// Mapping translates user or group IDs between a user namespace and the
+// host, following the semantics of /proc/<pid>/uid_map and gid_map.
+type Mapping []Range
+
+// Parse reads a mapping in the format of /proc/<pid>/uid_map: one range per
+// line, as the container ID, host ID and size separated by whitespace.
+func Parse(r io.Reader) (Mapping, error) {
+	var m Mapping
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid ID mapping on line %d: %q", line, scanner.Text())
+		}
+
+		var ids [3]int
+		for i, field := range fields {
+			id, err := strconv.ParseUint(field, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid ID mapping on line %d: %w", line, err)
+			}
+			ids[i] = int(id)
+		}
+
+		m = append(m, Range{ContainerID: ids[0], HostID: ids[1], Size: ids[2]})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// ToHost returns the host ID that containerID maps to, or OverflowID when it
+// is not mapped.
+func (m Mapping) ToHost(containerID int) int {
+	for _, r := range m {
+		if containerID >= r.ContainerID && containerID-r.ContainerID < r.Size {
+			return r.HostID + containerID - r.ContainerID
+		}
+	}
+
+	return OverflowID
+}
+
+// ToContainer returns the container ID that hostID maps to, or OverflowID
+// when it is not mapped.
+func (m Mapping) ToContainer(hostID int) int {
+	for _, r := range m {
+		if hostID >= r.HostID && hostID-r.HostID < r.Size {
+			return r.ContainerID + hostID - r.HostID
+		}
+	}
+
+	return OverflowID
+}
@@ -0,0 +1,13 @@
+package idmap_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestIdmap(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Idmap Suite")
+}
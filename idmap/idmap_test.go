@@ -0,0 +1,62 @@
+package idmap_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/archiver/idmap"
+)
+
+var _ = Describe("Mapping", func() {
+	mapping := idmap.Mapping{
+		{ContainerID: 0, HostID: 100000, Size: 1},
+		{ContainerID: 1, HostID: 200001, Size: 65535},
+	}
+
+	DescribeTable("ToHost",
+		func(containerID, hostID int) {
+			Expect(mapping.ToHost(containerID)).To(Equal(hostID))
+		},
+		Entry("the start of a range", 0, 100000),
+		Entry("inside a range", 1000, 201000),
+		Entry("the end of a range", 65535, 265535),
+		Entry("past the last range", 65536, idmap.OverflowID),
+	)
+
+	DescribeTable("ToContainer",
+		func(hostID, containerID int) {
+			Expect(mapping.ToContainer(hostID)).To(Equal(containerID))
+		},
+		Entry("the start of a range", 100000, 0),
+		Entry("inside a range", 201000, 1000),
+		Entry("between ranges", 100001, idmap.OverflowID),
+		Entry("a host ID that is not mapped", 0, idmap.OverflowID),
+	)
+
+	It("maps nothing when it is empty", func() {
+		Expect(idmap.Mapping{}.ToHost(0)).To(Equal(idmap.OverflowID))
+	})
+})
+
+var _ = Describe("Parse", func() {
+	It("reads the format of /proc/self/uid_map", func() {
+		mapping, err := idmap.Parse(strings.NewReader("         0     100000          1\n         1     200001      65535\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mapping).To(Equal(idmap.Mapping{
+			{ContainerID: 0, HostID: 100000, Size: 1},
+			{ContainerID: 1, HostID: 200001, Size: 65535},
+		}))
+	})
+
+	It("rejects lines without three fields", func() {
+		_, err := idmap.Parse(strings.NewReader("0 100000\n"))
+		Expect(err).To(MatchError(ContainSubstring("line 1")))
+	})
+
+	It("rejects IDs that are not numbers", func() {
+		_, err := idmap.Parse(strings.NewReader("0 100000 1\nroot 0 1\n"))
+		Expect(err).To(MatchError(ContainSubstring("line 2")))
+	})
+})
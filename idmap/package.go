@@ -0,0 +1 @@
+package idmap // import "code.cloudfoundry.org/archiver/idmap"
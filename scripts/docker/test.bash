@@ -16,6 +16,15 @@ function test() {
   /ci/shared/tasks/run-bin-test/task.bash "${sub_package}"
 }
 
+function cross_compile() {
+  pushd "${REPO_PATH}" > /dev/null
+  for goos in linux darwin freebsd netbsd openbsd solaris windows; do
+    echo "Vetting for ${goos}"
+    GOOS="${goos}" GOFLAGS="-buildvcs=false" go vet ./...
+  done
+  popd > /dev/null
+}
+
 pushd / > /dev/null
 if [[ -n "${1:-}" ]]; then
   test "${1}" "${2:-}"
@@ -23,3 +32,5 @@ else
   test "."
 fi
 popd > /dev/null
+
+cross_compile